@@ -0,0 +1,379 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// blocking rule, plain JSON string is a legacy substring rule, categories and except
+// are checked against the final category (after categorization and overrides)
+//
+//	"jarmark"
+//	{"word": "sýr"}
+//	{"regex": "\\bvín[oa]\\b", "categories": ["OSTATNÍ"]}
+//	{"word": "protein", "except": ["VITAMÍNY"], "allow": ["proteinový šampon"]}
+type BlockRule struct {
+	Contains   string   `json:"contains,omitempty"`   // substring of the lowercased text
+	Word       string   `json:"word,omitempty"`       // whole word(s), diacritics ignored
+	Regex      string   `json:"regex,omitempty"`      // case insensitive regexp
+	Categories []string `json:"categories,omitempty"` // only in these categories
+	Except     []string `json:"except,omitempty"`     // never in these categories
+	Allow      []string `json:"allow,omitempty"`      // texts with any of these words are kept
+
+	re    *regexp.Regexp
+	word  string
+	allow []string
+}
+
+// item removed by a rule
+type BlockedItem struct {
+	Rule     string `json:"rule"`
+	Field    string `json:"field"`
+	Text     string `json:"text"`
+	Name     string `json:"name"`
+	Category string `json:"cat"`
+	Query    string `json:"query"`
+}
+
+// removed items of the current run
+var (
+	blockedItems []BlockedItem
+	blockedMutex sync.Mutex
+)
+
+// UnmarshalJSON - accept plain strings as substring rules
+func (r *BlockRule) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*r = BlockRule{Contains: s}
+		return nil
+	}
+	type plain BlockRule
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*r = BlockRule(p)
+	return nil
+}
+
+// MarshalJSON - write substring-only rules back as plain strings
+func (r BlockRule) MarshalJSON() ([]byte, error) {
+	if r.Word == "" && r.Regex == "" && len(r.Categories) == 0 && len(r.Except) == 0 && len(r.Allow) == 0 {
+		return json.Marshal(r.Contains)
+	}
+	type plain BlockRule
+	return json.Marshal(plain(r))
+}
+
+// String - short rule description for reports
+func (r BlockRule) String() string {
+	var s string
+	switch {
+	case r.Word != "":
+		s = fmt.Sprintf("word %q", r.Word)
+	case r.Regex != "":
+		s = fmt.Sprintf("regex %q", r.Regex)
+	default:
+		s = fmt.Sprintf("%q", r.Contains)
+	}
+	if len(r.Categories) > 0 {
+		s += fmt.Sprintf(" in %v", r.Categories)
+	}
+	if len(r.Except) > 0 {
+		s += fmt.Sprintf(" except %v", r.Except)
+	}
+	if len(r.Allow) > 0 {
+		s += fmt.Sprintf(" allow %v", r.Allow)
+	}
+	return s
+}
+
+// validate - check the rule, returns problem description
+func (r BlockRule) validate() string {
+	kinds := 0
+	for _, v := range []string{r.Contains, r.Word, r.Regex} {
+		if v != "" {
+			kinds++
+		}
+	}
+	switch {
+	case kinds == 0:
+		return "empty rule"
+	case kinds > 1:
+		return "use only one of contains, word or regex"
+	case r.Contains != "" && r.Contains != strings.TrimSpace(r.Contains):
+		return fmt.Sprintf("leading or trailing space in %q", r.Contains)
+	case r.Word != "" && normalizeCzechString(r.Word) == "":
+		return fmt.Sprintf("word %q has no letters or digits", r.Word)
+	}
+	if r.Regex != "" {
+		if _, err := regexp.Compile("(?i)" + r.Regex); err != nil {
+			return fmt.Sprintf("bad regex %q: %v", r.Regex, err)
+		}
+	}
+	return ""
+}
+
+// compile - prepare the rule for matching
+func (r *BlockRule) compile() {
+	r.Contains = strings.ToLower(r.Contains)
+	r.word = ""
+	if r.Word != "" {
+		r.word = " " + normalizeCzechString(r.Word) + " "
+	}
+	r.re = nil
+	if r.Regex != "" {
+		r.re = regexp.MustCompile("(?i)" + r.Regex)
+	}
+	r.allow = nil
+	for _, a := range r.Allow {
+		r.allow = append(r.allow, " "+normalizeCzechString(a)+" ")
+	}
+}
+
+// scoped - check if the rule depends on the category
+func (r *BlockRule) scoped() bool {
+	return len(r.Categories) > 0 || len(r.Except) > 0
+}
+
+// matches - check the rule against the text in the category
+func (r *BlockRule) matches(text string, category string) bool {
+	if len(r.Categories) > 0 && !containsFold(r.Categories, category) {
+		return false
+	}
+	if containsFold(r.Except, category) {
+		return false
+	}
+
+	normalized := " " + normalizeCzechString(text) + " "
+	for _, a := range r.allow {
+		if strings.Contains(normalized, a) {
+			return false
+		}
+	}
+
+	switch {
+	case r.word != "":
+		return strings.Contains(normalized, r.word)
+	case r.re != nil:
+		return r.re.MatchString(text)
+	default:
+		return strings.Contains(strings.ToLower(text), r.Contains)
+	}
+}
+
+// containsFold - helper function for case insensitive list lookup
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRules - helper function to check blocking rules
+func validateRules(name string, rules []BlockRule) []string {
+	var problems []string
+	seen := make(map[string]int)
+	for i, rule := range rules {
+		if p := rule.validate(); p != "" {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %s", name, i, p))
+			continue
+		}
+		key := strings.ToLower(rule.String())
+		if j, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %s duplicates entry %d", name, i, rule, j))
+		} else {
+			seen[key] = i
+		}
+	}
+	return problems
+}
+
+// compileRules - helper function to prepare all rules for matching
+func compileRules(rules []BlockRule) {
+	for i := range rules {
+		rules[i].compile()
+	}
+}
+
+// findRule - first rule matching the text, nil if none
+func findRule(text string, category string, rules []BlockRule) *BlockRule {
+	for i := range rules {
+		if rules[i].matches(text, category) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// isForbidden - helper function to check the text against unscoped blocking rules while scraping, removed items are recorded
+func isForbidden(text string, field string, g Goods, rules []BlockRule) bool {
+	return blockByRules(text, field, g, rules, false)
+}
+
+// blockScopedGoods - remove goods matching the category scoped rules, the category has to be final
+func blockScopedGoods(goods []Goods) []Goods {
+	var kept []Goods
+	for _, g := range goods {
+		if blockByRules(g.Name, "name", g, config.BlockedGoods, true) ||
+			blockByRules(g.Note, "note", g, config.BlockedGoods, true) ||
+			blockByRules(g.Market, "market", g, config.BlockedMarkets, true) {
+			continue
+		}
+		kept = append(kept, g)
+	}
+	if removed := len(goods) - len(kept); removed > 0 {
+		log.Printf("🚫 %d items blocked by category scoped rules", removed)
+	}
+	return kept
+}
+
+// blockByRules - helper function to check the text against scoped or unscoped rules, removed items are recorded
+func blockByRules(text string, field string, g Goods, rules []BlockRule, scoped bool) bool {
+	var rule *BlockRule
+	for i := range rules {
+		if rules[i].scoped() == scoped && rules[i].matches(text, g.Category) {
+			rule = &rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return false
+	}
+	blockedMutex.Lock()
+	blockedItems = append(blockedItems, BlockedItem{
+		Rule:     rule.String(),
+		Field:    field,
+		Text:     text,
+		Name:     g.Name,
+		Category: g.Category,
+		Query:    g.Query,
+	})
+	blockedMutex.Unlock()
+	return true
+}
+
+// saveBlockReport - save items removed in this run
+func saveBlockReport(filename string) {
+	blockedMutex.Lock()
+	defer blockedMutex.Unlock()
+
+	// the same item is found by many queries and pages
+	seen := make(map[BlockedItem]bool)
+	var unique []BlockedItem
+	for _, item := range blockedItems {
+		key := item
+		key.Query = ""
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, item)
+		}
+	}
+	blockedItems = unique
+
+	sort.Slice(blockedItems, func(i, j int) bool {
+		if blockedItems[i].Rule != blockedItems[j].Rule {
+			return blockedItems[i].Rule < blockedItems[j].Rule
+		}
+		return blockedItems[i].Text < blockedItems[j].Text
+	})
+	content, err := json.MarshalIndent(blockedItems, "", "  ")
+	if err != nil {
+		log.Fatalf("[%s] 💥 error encoding: %v", filename, err)
+	}
+	if err := os.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+		log.Fatalf("[%s] 💥 error writing: %v", filename, err)
+	}
+	log.Printf("🚫 %d blocked items saved to %s", len(blockedItems), filename)
+}
+
+// rulesCommand - dry run of the current rules against the items of the last run
+func rulesCommand() {
+	type ruleHits struct {
+		rule  string
+		names map[string]bool
+	}
+	hits := make(map[string]*ruleHits)
+	var order []string
+	for _, rules := range [][]BlockRule{config.BlockedGoods, config.BlockedMarkets} {
+		for _, r := range rules {
+			if _, ok := hits[r.String()]; !ok {
+				hits[r.String()] = &ruleHits{rule: r.String(), names: make(map[string]bool)}
+				order = append(order, r.String())
+			}
+		}
+	}
+
+	// items blocked in the last run
+	var lastBlocked []BlockedItem
+	if content, err := os.ReadFile(config.Paths.BlockReport); err == nil {
+		if err := json.Unmarshal(content, &lastBlocked); err != nil {
+			log.Printf("[%s] 💥 error parsing: %v", config.Paths.BlockReport, err)
+		}
+	}
+
+	// items kept in the last run
+	kept, err := loadGoodsFromJson(config.Paths.OutputJson)
+	if err != nil {
+		log.Printf("[%s] 💥 error loading: %v", config.Paths.OutputJson, err)
+	}
+
+	record := func(rule *BlockRule, label string) bool {
+		if rule == nil {
+			return false
+		}
+		hits[rule.String()].names[label] = true
+		return true
+	}
+
+	var released []string
+	for _, item := range lastBlocked {
+		rules := config.BlockedGoods
+		if item.Field == "market" {
+			rules = config.BlockedMarkets
+		}
+		if !record(findRule(item.Text, item.Category, rules), item.Text) {
+			released = append(released, fmt.Sprintf("%s (%s, was %s)", item.Text, item.Category, item.Rule))
+		}
+	}
+	newlyBlocked := 0
+	for _, g := range kept {
+		if record(findRule(g.Name, g.Category, config.BlockedGoods), g.Name) ||
+			record(findRule(g.Note, g.Category, config.BlockedGoods), g.Name+" | "+g.Note) ||
+			record(findRule(g.Market, g.Category, config.BlockedMarkets), g.Market) {
+			newlyBlocked++
+		}
+	}
+
+	for _, key := range order {
+		h := hits[key]
+		if len(h.names) == 0 {
+			fmt.Printf("💤 %s\n", h.rule)
+			continue
+		}
+		var names []string
+		for name := range h.names {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("🚫 %s%s%s (%d)\n", ColorBold, h.rule, ColorReset, len(names))
+		for _, name := range names {
+			fmt.Printf("    %s\n", name)
+		}
+	}
+
+	sort.Strings(released)
+	for _, r := range released {
+		fmt.Printf("🟢 no longer blocked: %s\n", r)
+	}
+	fmt.Printf("\n🧪 Dry run: %d items blocked last run, %d would be released, %d kept items would be blocked.\n\n", len(lastBlocked), len(released), newlyBlocked)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// category definition, groups are categories with children
+//
+//	{"id": "PÉČE", "name": "Péče", "icon": "💆"}
+//	{"id": "VLASY", "name": "Vlasy", "icon": "💇", "parent": "PÉČE", "aliases": ["ŠAMPONY"], "brands": ["schauma"]}
+//
+// brands and queries of the category with higher priority win the deterministic category assignment
+type Category struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Icon        string   `json:"icon"`
+	Description string   `json:"description,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Brands      []string `json:"brands,omitempty"`
+	Priority    int      `json:"priority,omitempty"`
+}
+
+// category in koopi.json
+type CategoryExport struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Icon        string   `json:"icon"`
+	Description string   `json:"desc"`
+	Parent      string   `json:"parent"`
+	Children    []string `json:"children"`
+	Count       int      `json:"count"`
+}
+
+// ordered categories with alias lookup
+type Taxonomy struct {
+	list  []Category
+	index map[string]string // upper case id or alias → id
+}
+
+// current taxonomy
+var taxonomy Taxonomy
+
+// readTaxonomy - read the category file
+func readTaxonomy(filename string) (Taxonomy, error) {
+	var t Taxonomy
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(content, &t.list); err != nil {
+		return t, err
+	}
+	t.index = make(map[string]string)
+	for _, c := range t.list {
+		t.index[strings.ToUpper(c.Id)] = c.Id
+	}
+	for _, c := range t.list {
+		for _, a := range c.Aliases {
+			if _, ok := t.index[strings.ToUpper(a)]; !ok {
+				t.index[strings.ToUpper(a)] = c.Id
+			}
+		}
+	}
+	return t, nil
+}
+
+// validate - list malformed category entries
+func (t Taxonomy) validate() []string {
+	var problems []string
+	seen := make(map[string]string)
+	brands := make(map[string]string)
+	parents := make(map[string]string)
+	for i, c := range t.list {
+		name := fmt.Sprintf("categories[%d]", i)
+		if strings.TrimSpace(c.Id) == "" {
+			problems = append(problems, fmt.Sprintf("%s: empty id", name))
+			continue
+		}
+		if c.Name == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s has no name", name, c.Id))
+		}
+		if c.Icon == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s has no icon", name, c.Id))
+		}
+		for _, key := range append([]string{c.Id}, c.Aliases...) {
+			upper := strings.ToUpper(key)
+			if other, ok := seen[upper]; ok {
+				problems = append(problems, fmt.Sprintf("%s: %q is already used by %s", name, key, other))
+			} else {
+				seen[upper] = c.Id
+			}
+		}
+		for _, b := range c.Brands {
+			key := strings.ToLower(strings.TrimSpace(b))
+			if key == "" || key != strings.ToLower(b) {
+				problems = append(problems, fmt.Sprintf("%s: %s has empty or padded brand %q", name, c.Id, b))
+			} else if other, ok := brands[key]; ok {
+				problems = append(problems, fmt.Sprintf("%s: brand %q is already used by %s", name, b, other))
+			} else {
+				brands[key] = c.Id
+			}
+		}
+		parents[c.Id] = c.Parent
+	}
+
+	for _, c := range t.list {
+		if c.Parent == "" {
+			continue
+		}
+		if _, ok := parents[c.Parent]; !ok {
+			problems = append(problems, fmt.Sprintf("categories: %s has unknown parent %s", c.Id, c.Parent))
+			continue
+		}
+		// parent chain must end
+		visited := map[string]bool{c.Id: true}
+		for p := c.Parent; p != ""; p = parents[p] {
+			if visited[p] {
+				problems = append(problems, fmt.Sprintf("categories: %s has a parent cycle", c.Id))
+				break
+			}
+			visited[p] = true
+		}
+	}
+	return problems
+}
+
+// loadTaxonomy - load the category file into the current taxonomy
+func loadTaxonomy(filename string) error {
+	t, err := readTaxonomy(filename)
+	if err != nil {
+		return err
+	}
+	taxonomy = t
+	return nil
+}
+
+// resolve - category id of the name or alias, unknown names are returned unchanged
+func (t Taxonomy) resolve(name string) string {
+	if id, ok := t.index[strings.ToUpper(name)]; ok {
+		return id
+	}
+	return name
+}
+
+// known - check if the category id exists
+func (t Taxonomy) known(id string) bool {
+	return t.index[strings.ToUpper(id)] == id
+}
+
+// export - ordered category list with item counts, parents count their children
+func (t Taxonomy) export(counts map[string]int) []CategoryExport {
+	var list []CategoryExport
+	position := make(map[string]int)
+	for _, c := range t.list {
+		position[c.Id] = len(list)
+		list = append(list, CategoryExport{
+			Id:          c.Id,
+			Name:        c.Name,
+			Icon:        c.Icon,
+			Description: c.Description,
+			Parent:      c.Parent,
+			Children:    []string{},
+			Count:       counts[c.Id],
+		})
+	}
+	for _, c := range t.list {
+		if c.Parent != "" {
+			list[position[c.Parent]].Children = append(list[position[c.Parent]].Children, c.Id)
+		}
+	}
+
+	// add counts to all ancestors
+	for _, c := range t.list {
+		for p := c.Parent; p != ""; p = t.parent(p) {
+			list[position[p]].Count += counts[c.Id]
+		}
+	}
+
+	// categories missing in the taxonomy still have to show up
+	var unknown []string
+	for id := range counts {
+		if _, ok := position[id]; !ok {
+			unknown = append(unknown, id)
+		}
+	}
+	sort.Strings(unknown)
+	for _, id := range unknown {
+		log.Printf("❓ category %s%s%s is not in the taxonomy", ColorBold, id, ColorReset)
+		list = append(list, CategoryExport{Id: id, Name: id, Icon: "📦", Children: []string{}, Count: counts[id]})
+	}
+	return list
+}
+
+// priority - assignment priority of the category
+func (t Taxonomy) priority(id string) int {
+	for _, c := range t.list {
+		if c.Id == id {
+			return c.Priority
+		}
+	}
+	return 0
+}
+
+// parent - parent id of the category
+func (t Taxonomy) parent(id string) string {
+	for _, c := range t.list {
+		if c.Id == id {
+			return c.Parent
+		}
+	}
+	return ""
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// category assignment rule, the name must contain the pattern
+type CategoryRule struct {
+	Pattern  string // lower case
+	Category string
+	Source   string // query or brand
+	Priority int
+	Order    int
+}
+
+// String - short rule description for the trace
+func (r CategoryRule) String() string {
+	return fmt.Sprintf("%s %q → %s (priority %d, %d chars)", r.Source, r.Pattern, r.Category, r.Priority, utf8.RuneCountInString(r.Pattern))
+}
+
+// better - rule precedence: higher priority, longer pattern, earlier rule
+func (r CategoryRule) better(o CategoryRule) bool {
+	if r.Priority != o.Priority {
+		return r.Priority > o.Priority
+	}
+	if lr, lo := utf8.RuneCountInString(r.Pattern), utf8.RuneCountInString(o.Pattern); lr != lo {
+		return lr > lo
+	}
+	return r.Order < o.Order
+}
+
+// Aho-Corasick automaton node
+type acNode struct {
+	next map[rune]int
+	fail int
+	out  []int // rules ending here, including fail links
+}
+
+// Aho-Corasick matcher of all category rules
+type CategoryMatcher struct {
+	rules []CategoryRule
+	nodes []acNode
+}
+
+// newCategoryMatcher - build the automaton
+func newCategoryMatcher(rules []CategoryRule) *CategoryMatcher {
+	m := &CategoryMatcher{rules: rules, nodes: []acNode{{next: make(map[rune]int)}}}
+
+	// trie
+	for i, r := range rules {
+		node := 0
+		for _, c := range r.Pattern {
+			child, ok := m.nodes[node].next[c]
+			if !ok {
+				child = len(m.nodes)
+				m.nodes = append(m.nodes, acNode{next: make(map[rune]int)})
+				m.nodes[node].next[c] = child
+			}
+			node = child
+		}
+		m.nodes[node].out = append(m.nodes[node].out, i)
+	}
+
+	// fail links, breadth first
+	var queue []int
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[node].next {
+			fail := m.nodes[node].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[c]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[c]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// matchAll - indexes of all rules contained in the text
+func (m *CategoryMatcher) matchAll(text string) []int {
+	var found []int
+	seen := make(map[int]bool)
+	node := 0
+	for _, c := range strings.ToLower(text) {
+		for node != 0 {
+			if _, ok := m.nodes[node].next[c]; ok {
+				break
+			}
+			node = m.nodes[node].fail
+		}
+		node = m.nodes[node].next[c] // missing rune goes back to the root
+		for _, i := range m.nodes[node].out {
+			if !seen[i] {
+				seen[i] = true
+				found = append(found, i)
+			}
+		}
+	}
+	return found
+}
+
+// match - the winning rule and all matched rules, nil if nothing matches
+func (m *CategoryMatcher) match(text string) (*CategoryRule, []int) {
+	found := m.matchAll(text)
+	var best *CategoryRule
+	for _, i := range found {
+		if best == nil || m.rules[i].better(*best) {
+			best = &m.rules[i]
+		}
+	}
+	return best, found
+}
+
+// categoryRules - rules from the scrape.csv queries and the taxonomy brands
+func categoryRules(queries []ScrapeQuery, t Taxonomy) []CategoryRule {
+	var rules []CategoryRule
+	for _, q := range queries {
+		if q.Pages == 0 {
+			continue
+		}
+		rules = append(rules, CategoryRule{
+			Pattern:  strings.ToLower(q.Query),
+			Category: q.Category,
+			Source:   "query",
+			Priority: t.priority(q.Category),
+			Order:    len(rules),
+		})
+	}
+	for _, c := range t.list {
+		for _, b := range c.Brands {
+			rules = append(rules, CategoryRule{
+				Pattern:  strings.ToLower(b),
+				Category: c.Id,
+				Source:   "brand",
+				Priority: c.Priority,
+				Order:    len(rules),
+			})
+		}
+	}
+	return rules
+}
+
+// assignCategories - set the deterministic category of the goods and save the trace, returns matched items
+func assignCategories(goods []Goods, queries []ScrapeQuery, traceFile string) []bool {
+	matcher := newCategoryMatcher(categoryRules(queries, taxonomy))
+
+	file, err := os.Create(traceFile)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error opening for writing: %v", traceFile, err)
+	}
+	defer file.Close()
+	trace := bufio.NewWriter(file)
+
+	isMatched := make([]bool, len(goods))
+	matched, changed := 0, 0
+	for i := range goods {
+		g := &goods[i]
+		best, found := matcher.match(g.Name)
+		if best == nil {
+			g.CatSource = CAT_SOURCE_QUERY
+			fmt.Fprintf(trace, "%s\t%s\tno rule, kept from query %q\n", g.Category, g.Name, g.Query)
+			continue
+		}
+		g.CatSource = CAT_SOURCE_MATCH
+		isMatched[i] = true
+		matched++
+		reason := best.String()
+		if len(found) > 1 {
+			var others []string
+			for _, j := range found {
+				if &matcher.rules[j] != best {
+					others = append(others, matcher.rules[j].String())
+				}
+			}
+			reason += "; over " + strings.Join(others, ", ")
+		}
+		if g.Category != best.Category {
+			reason += fmt.Sprintf("; was %s from query %q", g.Category, g.Query)
+			g.Category = best.Category
+			changed++
+		}
+		fmt.Fprintf(trace, "%s\t%s\t%s\n", g.Category, g.Name, reason)
+	}
+	if err := trace.Flush(); err != nil {
+		log.Fatalf("[%s] 💥 error writing: %v", traceFile, err)
+	}
+
+	log.Printf("🏷️ categories: %d rules, %d matched, %s%d%s changed, %d kept, trace saved to %s",
+		len(matcher.rules), matched, ColorBold, changed, ColorReset, len(goods)-matched, traceFile)
+	return isMatched
+}
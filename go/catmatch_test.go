@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCategoryMatcherOverlaps(t *testing.T) {
+	// the classic Aho-Corasick example, "she" and "hers" end inside each other
+	m := newCategoryMatcher([]CategoryRule{{Pattern: "he"}, {Pattern: "she"}, {Pattern: "his"}, {Pattern: "hers"}})
+	got := m.matchAll("USHERS")
+	slices.Sort(got)
+	if want := []int{0, 1, 3}; !slices.Equal(got, want) {
+		t.Errorf("matchAll(USHERS) = %v, want %v", got, want)
+	}
+}
+
+func TestCategoryMatcherPrecedence(t *testing.T) {
+	m := newCategoryMatcher([]CategoryRule{
+		{Pattern: "šampon", Category: "VLASY", Order: 0},
+		{Pattern: "šampon pro psy", Category: "ZVÍŘATA", Order: 1},
+		{Pattern: "syoss", Category: "KOSMETIKA", Priority: 1, Order: 2},
+		{Pattern: "gel", Category: "KOUPEL", Order: 3},
+		{Pattern: "gel", Category: "PRANÍ", Order: 4},
+	})
+	for text, want := range map[string]string{
+		"Šampon pro psy 250 ml": "ZVÍŘATA",   // longer pattern
+		"Syoss šampon pro psy":  "KOSMETIKA", // higher priority
+		"Sprchový gel":          "KOUPEL",    // earlier rule
+	} {
+		if best, _ := m.match(text); best == nil || best.Category != want {
+			t.Errorf("match(%q) = %v, want %s", text, best, want)
+		}
+	}
+	if best, _ := m.match("kondicionér"); best != nil {
+		t.Errorf("match(kondicionér) = %v, want nil", best)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// confidence is a heuristic score of the suggestion, not a calibrated probability,
+// check the thresholds with 'koopi classify' after changing the scoring
+const (
+	CLASSIFY_APPLY_CONFIDENCE  = 0.6       // suggestion is applied to items without a deterministic match
+	CLASSIFY_REVIEW_CONFIDENCE = 0.3       // less confident suggestions go to the review report
+	CLASSIFY_FALLBACK          = "OSTATNÍ" // not used for training
+)
+
+// category sources saved with the goods
+const (
+	CAT_SOURCE_MATCH      = "match"      // deterministic rule
+	CAT_SOURCE_QUERY      = "query"      // scrape.csv query without a matching rule
+	CAT_SOURCE_CLASSIFIER = "classifier" // applied suggestion
+	CAT_SOURCE_OVERRIDE   = "override"   // manual override
+)
+
+// naive Bayes classifier of product names
+type Classifier struct {
+	docs   map[string]int            // category → products
+	words  map[string]map[string]int // category → word → products
+	totals map[string]int            // category → words
+	vocab  map[string]bool
+	total  int
+}
+
+// suggested category waiting for a human decision
+type ReviewItem struct {
+	Reason     string  `json:"reason"`
+	Name       string  `json:"name"`
+	Category   string  `json:"cat"`
+	Suggested  string  `json:"suggested"`
+	Confidence float64 `json:"confidence"`
+	Query      string  `json:"query"`
+}
+
+// classifierTokens - helper function to split the name into distinct words
+func classifierTokens(name string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	for w := range strings.FieldsSeq(normalizeCzechString(name)) {
+		if len(w) < 3 || strings.Trim(w, "0123456789") == "" || seen[w] {
+			continue
+		}
+		seen[w] = true
+		tokens = append(tokens, w)
+	}
+	return tokens
+}
+
+// newClassifier - empty classifier
+func newClassifier() *Classifier {
+	return &Classifier{
+		docs:   make(map[string]int),
+		words:  make(map[string]map[string]int),
+		totals: make(map[string]int),
+		vocab:  make(map[string]bool),
+	}
+}
+
+// add - train the classifier with one product
+func (c *Classifier) add(name string, category string) {
+	tokens := classifierTokens(name)
+	if len(tokens) == 0 {
+		return
+	}
+	if c.words[category] == nil {
+		c.words[category] = make(map[string]int)
+	}
+	c.docs[category]++
+	c.total++
+	for _, t := range tokens {
+		c.words[category][t]++
+		c.totals[category]++
+		c.vocab[t] = true
+	}
+}
+
+// classify - most probable category and its heuristic confidence score
+func (c *Classifier) classify(name string) (string, float64) {
+	tokens := classifierTokens(name)
+	if c.total == 0 || len(tokens) == 0 {
+		return "", 0
+	}
+
+	// log probabilities with Laplace smoothing
+	scores := make(map[string]float64)
+	best, bestScore := "", math.Inf(-1)
+	vocab := float64(len(c.vocab))
+	for category, docs := range c.docs {
+		score := math.Log(float64(docs) / float64(c.total))
+		for _, t := range tokens {
+			score += math.Log(float64(c.words[category][t]+1) / (float64(c.totals[category]) + vocab))
+		}
+		scores[category] = score
+		if score > bestScore || (score == bestScore && category < best) {
+			best, bestScore = category, score
+		}
+	}
+
+	// softmax of the log scores scaled by the token count, raw naive Bayes probabilities are overconfident,
+	// the result orders the suggestions but it is not a probability
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp((score - bestScore) / float64(len(tokens)))
+	}
+	return best, 1 / sum
+}
+
+// trainedCategory - helper function to get the category the classifier may learn, never its own suggestions,
+// stems without the category source count only when the deterministic rules agree
+func trainedCategory(g Goods, matcher *CategoryMatcher) string {
+	switch g.CatSource {
+	case CAT_SOURCE_MATCH, CAT_SOURCE_OVERRIDE:
+		return g.Category
+	case "":
+		if best, _ := matcher.match(g.Name); best != nil && best.Category == g.Category {
+			return g.Category
+		}
+	}
+	return ""
+}
+
+// trainClassifier - train on the latest deterministic or manual category of every product in the stems
+func trainClassifier(dir string, queries []ScrapeQuery) (*Classifier, error) {
+	files, _, err := listStems(dir)
+	if err != nil {
+		return nil, err
+	}
+	products := make(map[string]Goods)
+	for _, file := range files {
+		goods, err := loadGoodsFromJson(filepath.Join(dir, file))
+		if err != nil {
+			log.Printf("[%s] 💥 error loading: %v", file, err)
+			continue
+		}
+		for _, g := range goods {
+			products[productHash(g)] = g
+		}
+	}
+
+	c := newClassifier()
+	matcher := newCategoryMatcher(categoryRules(queries, taxonomy))
+	for _, g := range products {
+		if category := trainedCategory(g, matcher); category != "" && category != CLASSIFY_FALLBACK {
+			c.add(g.Name, category)
+		}
+	}
+	return c, nil
+}
+
+// classifyGoods - suggest categories, apply confident suggestions to unmatched items and save the review report
+func classifyGoods(goods []Goods, matched []bool, queries []ScrapeQuery, reportFile string) {
+	c, err := trainClassifier(config.Paths.Stems, queries)
+	if err != nil {
+		log.Printf("🫥 [%s] no classifier: %v", config.Paths.Stems, err)
+		return
+	}
+
+	applied := 0
+	var review []ReviewItem
+	seen := make(map[ReviewItem]bool)
+	for i := range goods {
+		g := &goods[i]
+		g.SuggestedCat, g.CatConfidence = c.classify(g.Name)
+		g.CatConfidence = math.Round(g.CatConfidence*1000) / 1000
+		item := ReviewItem{
+			Name:       g.Name,
+			Category:   g.Category,
+			Suggested:  g.SuggestedCat,
+			Confidence: g.CatConfidence,
+			Query:      g.Query,
+		}
+		switch {
+		case g.SuggestedCat == "" || g.SuggestedCat == g.Category:
+			continue
+		case !matched[i] && g.CatConfidence >= CLASSIFY_APPLY_CONFIDENCE:
+			g.Category = g.SuggestedCat
+			g.CatSource = CAT_SOURCE_CLASSIFIER
+			applied++
+			continue
+		case matched[i] && g.CatConfidence >= CLASSIFY_APPLY_CONFIDENCE:
+			item.Reason = "conflict"
+		case g.CatConfidence >= CLASSIFY_REVIEW_CONFIDENCE:
+			continue
+		default:
+			item.Reason = "low confidence"
+		}
+		// the same item is offered by many markets
+		if !seen[item] {
+			seen[item] = true
+			review = append(review, item)
+		}
+	}
+
+	sort.Slice(review, func(i, j int) bool {
+		if review[i].Reason != review[j].Reason {
+			return review[i].Reason < review[j].Reason
+		}
+		return review[i].Name < review[j].Name
+	})
+	content, err := json.MarshalIndent(review, "", "  ")
+	if err != nil {
+		log.Fatalf("[%s] 💥 error encoding: %v", reportFile, err)
+	}
+	if err := os.WriteFile(reportFile, append(content, '\n'), 0644); err != nil {
+		log.Fatalf("[%s] 💥 error writing: %v", reportFile, err)
+	}
+
+	log.Printf("🧠 classifier: %d products trained, %s%d%s categories applied, %d items for review saved to %s",
+		c.total, ColorBold, applied, ColorReset, len(review), reportFile)
+}
+
+// classifyCommand - compare the categories of the exported items with the classifier suggestions
+func classifyCommand(args []string) {
+	input := config.Paths.OutputJson
+	if len(args) > 0 {
+		input = args[0]
+	}
+	goods, err := loadGoodsFromJson(input)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error loading: %v", input, err)
+	}
+	queries, _, err := readScrapeCsv(config.Paths.InputCsv)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error reading: %v", config.Paths.InputCsv, err)
+	}
+	c, err := trainClassifier(config.Paths.Stems, queries)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error training: %v", config.Paths.Stems, err)
+	}
+
+	agree, confident := 0, 0
+	for _, g := range goods {
+		suggested, confidence := c.classify(g.Name)
+		if suggested == g.Category {
+			agree++
+			continue
+		}
+		if confidence >= CLASSIFY_APPLY_CONFIDENCE {
+			confident++
+			fmt.Printf("🧠 %s%s%s %s → %s%s%s (%.2f)\n", ColorBold, g.Name, ColorReset, g.Category, ColorBlue, suggested, ColorReset, confidence)
+		}
+	}
+	fmt.Printf("\n🧠 [%s] %d items, %d agree with the classifier, %d confident disagreements (%d products trained).\n\n",
+		input, len(goods), agree, confident, c.total)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+)
+
+// club decoration of older exports
+var clubIcons = strings.NewReplacer("📱", "", "💳️", "", "💳", "")
+
+// stripClubIcons - helper function to remove baked-in emoji from club text
+func stripClubIcons(s string) string {
+	return sanitizeString(clubIcons.Replace(s))
+}
+
+// parseClub - parse the club condition into the loyalty program and its requirements
+func parseClub(g *Goods) {
+	g.ClubProgram = ""
+	g.ClubMember, g.ClubApp, g.ClubCard = false, false, false
+	if g.Club == "" {
+		return
+	}
+
+	if m := marketRegistry.lookup(g.Market); m != nil && m.Loyalty != nil {
+		g.ClubProgram = m.Loyalty.Id
+	}
+	text := strings.ToLower(g.Club)
+
+	// known program or membership words, an app alone is not a membership
+	g.ClubMember = g.ClubProgram != ""
+	for _, word := range []string{"člen", "klub", "club", "card", "kart"} {
+		if strings.Contains(text, word) {
+			g.ClubMember = true
+		}
+	}
+	// requirements come from the club text only, "pro členy klubu" names neither
+	if strings.Contains(text, "aplikac") {
+		g.ClubApp = true
+	}
+	if strings.Contains(text, "card") || strings.Contains(text, "kart") {
+		g.ClubCard = true
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestParseClub(t *testing.T) {
+	if err := loadMarkets("markets.json"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		market  string
+		club    string
+		program string
+		member  bool
+		app     bool
+		card    bool
+	}{
+		// club texts as stored by extractOffer, only Kaufland and Lidl name their program
+		{"Kaufland", "Kaufland Card", "kaufland-card", true, false, true},
+		{"Lidl", "aplikace Lidl Plus", "lidl-plus", true, true, false},
+		{"Tesco", "pro členy klubu", "", true, false, false},
+		{"Albert hypermarket", "pro členy klubu, max. 2 ks", "", true, false, false},
+		{"Lidl", "", "", false, false, false},
+	}
+	for _, tt := range tests {
+		g := Goods{Market: tt.market, Club: tt.club}
+		parseClub(&g)
+		if g.ClubProgram != tt.program || g.ClubMember != tt.member || g.ClubApp != tt.app || g.ClubCard != tt.card {
+			t.Errorf("parseClub(%q, %q) = %q member %t app %t card %t, want %q member %t app %t card %t", tt.market, tt.club,
+				g.ClubProgram, g.ClubMember, g.ClubApp, g.ClubCard, tt.program, tt.member, tt.app, tt.card)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+)
+
+const (
+	CONFIG_JSON = "config.json"
+)
+
+// output and cache paths
+type ConfigPaths struct {
+	HtmlCache     string `json:"html_cache"`
+	ImageCache    string `json:"image_cache"`
+	InputCsv      string `json:"input_csv"`
+	OutputCsv     string `json:"output_csv"`
+	OutputJson    string `json:"output_json"`
+	OutputHistory string `json:"output_history"`
+	Hashmap       string `json:"hashmap"`
+	Ids           string `json:"ids"`
+	HistoryDb     string `json:"history_db"`
+	Stems         string `json:"stems"`
+	BlockReport   string `json:"block_report"`
+	Categories    string `json:"categories"`
+	CategoryTrace string `json:"category_trace"`
+	ReviewReport  string `json:"review_report"`
+	Overrides     string `json:"overrides"`
+	Markets       string `json:"markets"`
+	MarketLogos   string `json:"market_logos"`
+	CrawlReport   string `json:"crawl_report"`
+}
+
+// configuration file structure
+type Config struct {
+	Paths          ConfigPaths    `json:"paths"`
+	UserAgents     []string       `json:"user_agents"`
+	BlockedMarkets []BlockRule    `json:"blocked_markets"`
+	BlockedGoods   []BlockRule    `json:"blocked_goods"`
+	NameRewrites   [][]string     `json:"name_rewrites"`
+	NoteRewrites   [][]string     `json:"note_rewrites"`
+	ClubRewrites   [][]string     `json:"club_rewrites"`
+	ScrapeDetails  bool           `json:"scrape_details"` // second crawl of the product detail pages
+	DetailPages    int            `json:"detail_pages"`   // upper bound of the detail pages per run
+	CrawlSections  []CrawlSection `json:"crawl_sections"`
+	CrawlMerge     bool           `json:"crawl_merge"` // crawled offers go to the regular scrape outputs
+
+	// regular price element of a listing offer, empty while no checked selector is known
+	OriginalPriceSelector string `json:"original_price_selector"`
+}
+
+// current configuration
+var config = Config{
+	Paths: ConfigPaths{
+		HtmlCache:     "../cache",
+		ImageCache:    "../images",
+		InputCsv:      "scrape.csv",
+		OutputCsv:     "koopi.csv",
+		OutputJson:    "koopi.json",
+		OutputHistory: "history.json",
+		Hashmap:       "../hashmap.json",
+		Ids:           "../ids.json",
+		HistoryDb:     "../history.db",
+		Stems:         "../stems",
+		BlockReport:   "blocked.json",
+		Categories:    "categories.json",
+		CategoryTrace: "categories.txt",
+		ReviewReport:  "review.json",
+		Overrides:     "overrides.json",
+		Markets:       "markets.json",
+		MarketLogos:   "../markets-v2",
+		CrawlReport:   "missed.json",
+	},
+	DetailPages: 77,
+}
+
+// readConfig - read configuration file over the defaults
+func readConfig(filename string) (Config, error) {
+	cfg := config
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// loadConfig - load and validate configuration file, exit on errors
+func loadConfig(filename string) error {
+	cfg, err := readConfig(filename)
+	if err != nil {
+		return err
+	}
+	if problems := validateConfig(cfg); len(problems) > 0 {
+		return fmt.Errorf("%d problems found, run 'koopi config' for details", len(problems))
+	}
+
+	compileRules(cfg.BlockedGoods)
+	compileRules(cfg.BlockedMarkets)
+	if err := loadTaxonomy(cfg.Paths.Categories); err != nil {
+		return err
+	}
+	if err := loadMarkets(cfg.Paths.Markets); err != nil {
+		return err
+	}
+
+	config = cfg
+	return nil
+}
+
+// validateList - helper function to check list entries
+func validateList(name string, list []string) []string {
+	var problems []string
+	seen := make(map[string]int)
+	for i, v := range list {
+		switch {
+		case strings.TrimSpace(v) == "":
+			problems = append(problems, fmt.Sprintf("%s[%d]: empty entry", name, i))
+		case v != strings.TrimSpace(v):
+			problems = append(problems, fmt.Sprintf("%s[%d]: leading or trailing space in %q", name, i, v))
+		}
+		key := strings.ToLower(v)
+		if j, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %q duplicates entry %d", name, i, v, j))
+		} else {
+			seen[key] = i
+		}
+	}
+	return problems
+}
+
+// validateRewrites - helper function to check rewrite pairs
+func validateRewrites(name string, rules [][]string) []string {
+	var problems []string
+	seen := make(map[string]int)
+	for i, rule := range rules {
+		if len(rule) != 2 {
+			problems = append(problems, fmt.Sprintf("%s[%d]: expected [from, to] pair, got %d values", name, i, len(rule)))
+			continue
+		}
+		if rule[0] == "" {
+			problems = append(problems, fmt.Sprintf("%s[%d]: empty search string", name, i))
+			continue
+		}
+		if rule[0] == rule[1] {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %q is replaced by itself", name, i, rule[0]))
+		}
+		if j, ok := seen[rule[0]]; ok {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %q duplicates entry %d", name, i, rule[0], j))
+		} else {
+			seen[rule[0]] = i
+		}
+	}
+	return problems
+}
+
+// validateConfig - list malformed configuration entries
+func validateConfig(cfg Config) []string {
+	var problems []string
+
+	paths := []struct {
+		name string
+		path string
+	}{
+		{"paths.html_cache", cfg.Paths.HtmlCache},
+		{"paths.image_cache", cfg.Paths.ImageCache},
+		{"paths.input_csv", cfg.Paths.InputCsv},
+		{"paths.output_csv", cfg.Paths.OutputCsv},
+		{"paths.output_json", cfg.Paths.OutputJson},
+		{"paths.output_history", cfg.Paths.OutputHistory},
+		{"paths.hashmap", cfg.Paths.Hashmap},
+		{"paths.ids", cfg.Paths.Ids},
+		{"paths.history_db", cfg.Paths.HistoryDb},
+		{"paths.stems", cfg.Paths.Stems},
+		{"paths.block_report", cfg.Paths.BlockReport},
+		{"paths.categories", cfg.Paths.Categories},
+		{"paths.category_trace", cfg.Paths.CategoryTrace},
+		{"paths.review_report", cfg.Paths.ReviewReport},
+		{"paths.overrides", cfg.Paths.Overrides},
+		{"paths.markets", cfg.Paths.Markets},
+		{"paths.market_logos", cfg.Paths.MarketLogos},
+		{"paths.crawl_report", cfg.Paths.CrawlReport},
+	}
+	for _, p := range paths {
+		if strings.TrimSpace(p.path) == "" {
+			problems = append(problems, fmt.Sprintf("%s: empty path", p.name))
+		}
+	}
+
+	t, err := readTaxonomy(cfg.Paths.Categories)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("paths.categories: %v", err))
+	} else {
+		problems = append(problems, t.validate()...)
+	}
+	if overrides, err := readOverrides(cfg.Paths.Overrides); err != nil {
+		problems = append(problems, fmt.Sprintf("paths.overrides: %v", err))
+	} else {
+		problems = append(problems, validateOverrides(overrides, t)...)
+	}
+	if r, err := readMarkets(cfg.Paths.Markets); err != nil {
+		problems = append(problems, fmt.Sprintf("paths.markets: %v", err))
+	} else {
+		problems = append(problems, r.validate()...)
+	}
+	if len(cfg.UserAgents) == 0 {
+		problems = append(problems, "user_agents: at least one UA is required")
+	}
+	problems = append(problems, validateList("user_agents", cfg.UserAgents)...)
+	problems = append(problems, validateRules("blocked_markets", cfg.BlockedMarkets)...)
+	problems = append(problems, validateRules("blocked_goods", cfg.BlockedGoods)...)
+	problems = append(problems, validateRewrites("name_rewrites", cfg.NameRewrites)...)
+	problems = append(problems, validateRewrites("note_rewrites", cfg.NoteRewrites)...)
+	problems = append(problems, validateRewrites("club_rewrites", cfg.ClubRewrites)...)
+	problems = append(problems, validateSections(cfg.CrawlSections)...)
+	if cfg.OriginalPriceSelector != "" {
+		if _, err := cascadia.Compile(cfg.OriginalPriceSelector); err != nil {
+			problems = append(problems, fmt.Sprintf("original_price_selector: %v", err))
+		}
+	}
+	if cfg.ScrapeDetails && cfg.DetailPages < 1 {
+		problems = append(problems, "detail_pages: at least 1 page is required with scrape_details")
+	}
+
+	return problems
+}
+
+// applyRewrites - apply rewrite pairs in order
+func applyRewrites(s string, rules [][]string) string {
+	for _, rule := range rules {
+		if len(rule) == 2 && rule[0] != "" {
+			s = strings.ReplaceAll(s, rule[0], rule[1])
+		}
+	}
+	return s
+}
+
+// configCommand - validate the configuration file and report malformed entries
+func configCommand(args []string) bool {
+	filename := CONFIG_JSON
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	cfg, err := readConfig(filename)
+	if err != nil {
+		fmt.Printf("💥 [%s] %v\n", filename, err)
+		return false
+	}
+	problems := validateConfig(cfg)
+	for _, p := range problems {
+		fmt.Printf("⚠️ %s\n", p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("\n❌ [%s] %d problems found.\n", filename, len(problems))
+		return false
+	}
+	t, _ := readTaxonomy(cfg.Paths.Categories)
+	r, _ := readMarkets(cfg.Paths.Markets)
+	fmt.Printf("✅ [%s] %d UAs, %d categories, %d markets, %d blocked markets, %d blocked goods, %d rewrites.\n", filename,
+		len(cfg.UserAgents), len(t.list), len(r.list), len(cfg.BlockedMarkets), len(cfg.BlockedGoods),
+		len(cfg.NameRewrites)+len(cfg.NoteRewrites)+len(cfg.ClubRewrites))
+	return true
+}
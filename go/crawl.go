@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+const (
+	KOOPI_SECTION_SUBPAGE = "?page="
+
+	MAX_CRAWL_SECTIONS = 77
+)
+
+// RegExps
+var (
+	// sekce slev, malá písmena, číslice a pomlčky
+	reSectionPath = regexp.MustCompile(`^/slevy(?:/[a-z0-9-]+)+$`)
+)
+
+// kupi.cz category listing to crawl, child sections below the path are discovered
+//
+//	{"path": "/slevy/drogerie", "pages": 5}
+type CrawlSection struct {
+	Path  string `json:"path"`
+	Pages int    `json:"pages"` // upper bound of listing pages per section
+}
+
+// product found by the crawl that no scrape.csv query catches (approximation, see caughtByQueries)
+type MissedProduct struct {
+	Name     string   `json:"name"`
+	Url      string   `json:"url"`
+	Section  string   `json:"section"`
+	Category string   `json:"cat,omitempty"` // category of the matching taxonomy rule
+	Markets  []string `json:"markets"`
+}
+
+// validateSections - list malformed crawl sections
+func validateSections(sections []CrawlSection) []string {
+	var problems []string
+	seen := make(map[string]int)
+	for i, s := range sections {
+		name := fmt.Sprintf("crawl_sections[%d]", i)
+		if !reSectionPath.MatchString(strings.TrimSuffix(s.Path, "/")) {
+			problems = append(problems, fmt.Sprintf("%s: path %q has to be a kupi.cz section like /slevy/drogerie", name, s.Path))
+		}
+		if s.Pages < 1 {
+			problems = append(problems, fmt.Sprintf("%s: %s needs at least 1 page", name, s.Path))
+		}
+		key := strings.TrimSuffix(strings.ToLower(s.Path), "/")
+		if j, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s duplicates entry %d", name, s.Path, j))
+		} else {
+			seen[key] = i
+		}
+	}
+	return problems
+}
+
+// sectionPage - URL and cache file of the listing page
+func sectionPage(path string, pageNum int) (string, string) {
+	urlStr := KOOPI_HOME_URL + path
+	if pageNum > 1 {
+		urlStr = fmt.Sprintf("%s%s%d", urlStr, KOOPI_SECTION_SUBPAGE, pageNum)
+	}
+	cacheKey := fmt.Sprintf("section-%s-%d.html", strings.ReplaceAll(strings.Trim(path, "/"), "/", "-"), pageNum)
+	return urlStr, cacheKey
+}
+
+// childSections - links to the direct subsections of the path, filtered and paged links are skipped
+func childSections(doc *goquery.Document, path string) []string {
+	var children []string
+	seen := make(map[string]bool)
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		href = strings.TrimPrefix(href, KOOPI_HOME_URL)
+		href, _, _ = strings.Cut(href, "#")
+		if strings.Contains(href, "?") {
+			return
+		}
+		href = strings.TrimSuffix(href, "/")
+		child, ok := strings.CutPrefix(href, prefix)
+		if !ok || strings.Contains(child, "/") || !reSectionPath.MatchString(href) || seen[href] {
+			return
+		}
+		seen[href] = true
+		children = append(children, href)
+	})
+	return children
+}
+
+// crawlSection - scrape the listing pages of the section (cache/online), returns goods and child sections,
+// the next page is followed only when the page links to it and adds new offers
+func crawlSection(UA string, ctx context.Context, section CrawlSection) ([]Goods, []string) {
+	var goods []Goods
+	var children []string
+	offers := make(map[string]bool)
+	last := 1
+	for pageNum := 1; pageNum <= min(section.Pages, last); pageNum++ {
+		urlStr, cacheKey := sectionPage(section.Path, pageNum)
+		scrapedAt := time.Now().Format(DATE_SCRAPED)
+		doc, err := loadHtmlFromCache(cacheKey)
+		if err == nil {
+			if info, err := os.Stat(filepath.Join(config.Paths.HtmlCache, cacheKey)); err == nil {
+				scrapedAt = info.ModTime().Format(DATE_SCRAPED)
+			}
+		} else {
+			bodyBytes := fetchPage(UA, ctx, urlStr, section.Path)
+			if bodyBytes == nil {
+				break
+			}
+			doc, err = goquery.NewDocumentFromReader(bytes.NewReader(bodyBytes))
+			if err != nil {
+				log.Printf("[%s] 😵‍💫 error creating document: %v", section.Path, err)
+				break
+			}
+			saveHtmlToCache(cacheKey, bodyBytes)
+		}
+
+		if pageNum == 1 {
+			children = childSections(doc, section.Path)
+		}
+		last = max(last, discoverPages(doc, pageNum))
+		pageGoods := extractGoodsFromHtml(doc, CLASSIFY_FALLBACK, section.Path, scrapedAt)
+		log.Printf("🗂️ %s %s+%d%s %s%s%s", section.Path, ColorBlue, len(pageGoods), ColorReset, ColorCyan, urlStr, ColorReset)
+
+		// the listing ended before the page limit
+		if len(pageGoods) == 0 {
+			break
+		}
+
+		// the same offers again, the listing ignores the page parameter
+		fresh := 0
+		for _, g := range pageGoods {
+			key := g.Url + "\t" + g.Market
+			if !offers[key] {
+				offers[key] = true
+				fresh++
+			}
+		}
+		if fresh == 0 {
+			log.Printf("⚠️ [%s] page %d repeats the previous pages, paging stopped", section.Path, pageNum)
+			break
+		}
+		goods = append(goods, pageGoods...)
+	}
+	return goods, children
+}
+
+// crawlSections - walk the section tree level by level
+func crawlSections(UA string, ctx context.Context, roots []CrawlSection) []Goods {
+	var allGoods []Goods
+	var mutex sync.Mutex
+	seen := make(map[string]bool)
+	level := make([]CrawlSection, 0, len(roots))
+	for _, s := range roots {
+		s.Path = strings.TrimSuffix(s.Path, "/")
+		if !seen[s.Path] {
+			seen[s.Path] = true
+			level = append(level, s)
+		}
+	}
+
+	for len(level) > 0 && ctx.Err() == nil {
+		var next []CrawlSection
+		var wg sync.WaitGroup
+		concurrencyLimit := make(chan struct{}, MAX_THREADS)
+		for _, section := range level {
+			wg.Add(1)
+			concurrencyLimit <- struct{}{}
+			go func(section CrawlSection) {
+				defer func() {
+					<-concurrencyLimit
+					wg.Done()
+				}()
+				goods, children := crawlSection(UA, ctx, section)
+				mutex.Lock()
+				defer mutex.Unlock()
+				allGoods = append(allGoods, goods...)
+				for _, child := range children {
+					if seen[child] || len(seen) >= MAX_CRAWL_SECTIONS {
+						continue
+					}
+					seen[child] = true
+					// child sections inherit the page limit
+					next = append(next, CrawlSection{Path: child, Pages: section.Pages})
+				}
+			}(section)
+		}
+		wg.Wait()
+		level = next
+	}
+
+	log.Printf("🗂️ crawled %d sections, %d offers", len(seen), len(allGoods))
+	return allGoods
+}
+
+// caughtByQueries - check if any scrape.csv query would find and keep the goods, approximation
+// of the kupi.cz search by the query contained in the name
+func caughtByQueries(g Goods, queries []ScrapeQuery) bool {
+	text := normalizeCzechString(g.Name)
+	for _, q := range queries {
+		if q.Pages == 0 {
+			continue
+		}
+		if strings.Contains(text, normalizeCzechString(q.Query)) && q.Filter.accepts(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// missedProducts - products of the crawl that the queries do not catch
+func missedProducts(goods []Goods, queries []ScrapeQuery) []MissedProduct {
+	categories := newCategoryMatcher(categoryRules(nil, taxonomy))
+	products := make(map[string]*MissedProduct)
+	for _, g := range goods {
+		if caughtByQueries(g, queries) {
+			continue
+		}
+		key := g.Url
+		if key == "" {
+			key = g.Name
+		}
+		p, ok := products[key]
+		if !ok {
+			p = &MissedProduct{
+				Name:    g.Name,
+				Url:     strings.TrimPrefix(g.Url, KOOPI_HOME_URL),
+				Section: g.Query,
+				Markets: []string{},
+			}
+			if best, _ := categories.match(g.Name); best != nil {
+				p.Category = best.Category
+			}
+			products[key] = p
+		}
+		if g.Market != "" && !containsFold(p.Markets, g.Market) {
+			p.Markets = append(p.Markets, g.Market)
+		}
+	}
+
+	var missed []MissedProduct
+	for _, p := range products {
+		sort.Strings(p.Markets)
+		missed = append(missed, *p)
+	}
+	c := collate.New(language.Czech, collate.IgnoreCase)
+	sort.Slice(missed, func(i, j int) bool {
+		if missed[i].Section != missed[j].Section {
+			return missed[i].Section < missed[j].Section
+		}
+		return c.CompareString(missed[i].Name, missed[j].Name) < 0
+	})
+	return missed
+}
+
+// crawlCommand - crawl the category sections and report products missed by the scrape.csv queries,
+// the report does not change the outputs, crawl_merge adds the sections to the regular scrape
+func crawlCommand() {
+	if len(config.CrawlSections) == 0 {
+		log.Println("🍀 No sections to crawl.")
+		return
+	}
+
+	queries, problems, err := readScrapeCsv(config.Paths.InputCsv)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error reading: %v", config.Paths.InputCsv, err)
+	}
+	for _, p := range problems {
+		log.Printf("⚠️ [%s] %s", config.Paths.InputCsv, p)
+	}
+
+	// set random UA and rate limiter
+	UA := config.UserAgents[rand.Intn(len(config.UserAgents))]
+	rateLimiter = make(chan struct{}, MAX_THREADS)
+	for range MAX_THREADS {
+		rateLimiter <- struct{}{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// signals handling
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Println("\n\nInterrupted ...")
+		cancel()
+	}()
+
+	goods := deduplicateGoods(crawlSections(UA, ctx, config.CrawlSections))
+	missed := missedProducts(goods, queries)
+	for _, p := range missed {
+		category := ""
+		if p.Category != "" {
+			category = fmt.Sprintf(" %s(%s)%s", ColorBlue, p.Category, ColorReset)
+		}
+		fmt.Printf("🕳️ %s %s%s%s%s %s%s%s\n", p.Section, ColorBold, p.Name, ColorReset, category, ColorCyan, p.Url, ColorReset)
+	}
+
+	content, err := json.MarshalIndent(missed, "", "  ")
+	if err != nil {
+		log.Fatalf("[%s] 💥 error encoding: %v", config.Paths.CrawlReport, err)
+	}
+	if err := os.WriteFile(config.Paths.CrawlReport, append(content, '\n'), 0644); err != nil {
+		log.Fatalf("[%s] 💥 error writing: %v", config.Paths.CrawlReport, err)
+	}
+	fmt.Printf("\n🗂️ %d offers crawled, %s%d%s products probably missed by %d queries (name does not contain the query, the kupi.cz search may differ), saved to %s.\n\n",
+		len(goods), ColorBold, len(missed), ColorReset, len(queries), config.Paths.CrawlReport)
+}
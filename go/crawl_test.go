@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestChildSections(t *testing.T) {
+	html := `
+		<a href="/slevy/drogerie/sampony">šampony</a>
+		<a href="https://www.kupi.cz/slevy/drogerie/zubni-pasty/">zubní pasty</a>
+		<a href="/slevy/drogerie/sampony#top">šampony</a>
+		<a href="/slevy/drogerie/sampony/kondicionery">nested</a>
+		<a href="/slevy/drogerie/sampony?page=2">paged</a>
+		<a href="/slevy/drogerie/Akce_2">not a section</a>
+		<a href="/slevy/drogerie">self</a>
+		<a href="/sleva/jar-citron">product</a>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/slevy/drogerie/sampony", "/slevy/drogerie/zubni-pasty"}
+	if got := childSections(doc, "/slevy/drogerie/"); !slices.Equal(got, want) {
+		t.Errorf("childSections = %q, want %q", got, want)
+	}
+}
+
+func TestValidateSections(t *testing.T) {
+	sections := []CrawlSection{
+		{Path: "/slevy/drogerie", Pages: 5},
+		{Path: "/slevy/drogerie/", Pages: 1}, // duplicate
+		{Path: "/hledej?f=jar", Pages: 1},
+		{Path: "/slevy/kosmetika", Pages: 0},
+	}
+	if problems := validateSections(sections); len(problems) != 3 {
+		t.Errorf("validateSections = %q, want 3 problems", problems)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// product detail page with all offers of the product
+type ProductDetail struct {
+	Description   string
+	LargeImageUrl string
+	Offers        []Goods
+}
+
+// detailCacheName - cache file of the detail page, "/sleva/avivaz-azurit" → "sleva-avivaz-azurit.html"
+func detailCacheName(productUrl string) string {
+	p := productUrl
+	if u, err := url.Parse(productUrl); err == nil {
+		p = u.Path
+	}
+	return strings.ReplaceAll(strings.Trim(p, "/"), "/", "-") + ".html"
+}
+
+// extractDetailFromHtml - extract description, large image and every offer of the product
+func extractDetailFromHtml(doc *goquery.Document, product Goods) ProductDetail {
+	var detail ProductDetail
+
+	// schema.org description and og:image, the page layout itself is not relied on
+	detail.Description = sanitizeString(doc.Find("[itemprop='description']").First().Text())
+
+	if img, ok := doc.Find("meta[property='og:image']").Attr("content"); ok && img != "" {
+		if !strings.HasPrefix(img, "http") {
+			img = KOOPI_IMAGE_URL + img
+		}
+		detail.LargeImageUrl = img
+	}
+
+	// current and upcoming offers share the row markup with the search results
+	doc.Find(".discount_row").Each(func(j int, offer *goquery.Selection) {
+		if newGoods, ok := extractOffer(offer, product); ok {
+			detail.Offers = append(detail.Offers, newGoods)
+		}
+	})
+	return detail
+}
+
+// scrapeDetail - scrape the detail page of the product (cache/online)
+func scrapeDetail(UA string, ctx context.Context, product Goods) (ProductDetail, bool) {
+	cacheName := detailCacheName(product.Url)
+	if doc, err := loadHtmlFromCache(cacheName); err == nil {
+		if info, err := os.Stat(filepath.Join(config.Paths.HtmlCache, cacheName)); err == nil {
+			product.ScrapedAt = info.ModTime().Format(DATE_SCRAPED)
+		}
+		return extractDetailFromHtml(doc, product), true
+	}
+
+	bodyBytes := fetchPage(UA, ctx, product.Url, product.Name)
+	if bodyBytes == nil {
+		return ProductDetail{}, false
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(bodyBytes))
+	if err != nil {
+		log.Printf("[%s] 😵‍💫 error creating document: %v", product.Name, err)
+		return ProductDetail{}, false
+	}
+	saveHtmlToCache(cacheName, bodyBytes)
+	product.ScrapedAt = time.Now().Format(DATE_SCRAPED)
+	return extractDetailFromHtml(doc, product), true
+}
+
+// detailProducts - one product per detail page, ranked by the query order in scrape.csv and then by
+// the number of offers, the page offers inherit only the product fields of the first offer
+func detailProducts(goods []Goods, queries []ScrapeQuery) []Goods {
+	rank := make(map[string]int)
+	for i, q := range queries {
+		if _, ok := rank[q.Query]; !ok {
+			rank[q.Query] = i
+		}
+	}
+	queryRank := func(query string) int {
+		if i, ok := rank[query]; ok {
+			return i
+		}
+		// crawled sections after the queries
+		return len(queries)
+	}
+
+	var products []Goods
+	offers := make(map[string]int)
+	for _, g := range goods {
+		if g.Url == "" {
+			continue
+		}
+		offers[g.Url]++
+		if offers[g.Url] > 1 {
+			continue
+		}
+		products = append(products, Goods{
+			Name:      g.Name,
+			Url:       g.Url,
+			ImageUrl:  g.ImageUrl,
+			Category:  g.Category,
+			Query:     g.Query,
+			ScrapedAt: g.ScrapedAt,
+		})
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		ri, rj := queryRank(products[i].Query), queryRank(products[j].Query)
+		if ri != rj {
+			return ri < rj
+		}
+		return offers[products[i].Url] > offers[products[j].Url]
+	})
+	return products
+}
+
+// scrapeDetails - merge offers, upcoming offers and descriptions from the detail pages of the scraped products
+func scrapeDetails(UA string, ctx context.Context, goods []Goods, queries []ScrapeQuery) []Goods {
+	filters := make(map[string]QueryFilter)
+	for _, q := range queries {
+		filters[q.Query] = q.Filter
+	}
+
+	products := detailProducts(goods, queries)
+	if len(products) > config.DetailPages {
+		log.Printf("✂️ detail pages: %d products, %d skipped, the first %d by query order and offers scraped (detail_pages)",
+			len(products), len(products)-config.DetailPages, config.DetailPages)
+		products = products[:config.DetailPages]
+	}
+
+	// uncached pages wait for the rate limiter
+	uncached := 0
+	for _, product := range products {
+		if _, err := os.Stat(filepath.Join(config.Paths.HtmlCache, detailCacheName(product.Url))); err != nil {
+			uncached++
+		}
+	}
+	if uncached > 0 {
+		expected := time.Duration(uncached*(SLEEP_STATIC_MS+SLEEP_RANDOM_MS/2)/MAX_THREADS) * time.Millisecond
+		log.Printf("⏳ detail pages: %d of %d not cached, about %s", uncached, len(products), expected.Round(time.Minute))
+	}
+
+	details := make(map[string]ProductDetail)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	concurrencyLimit := make(chan struct{}, MAX_THREADS)
+	for _, product := range products {
+		wg.Add(1)
+		concurrencyLimit <- struct{}{}
+		go func(product Goods) {
+			defer func() {
+				<-concurrencyLimit
+				wg.Done()
+			}()
+			if detail, ok := scrapeDetail(UA, ctx, product); ok {
+				mutex.Lock()
+				details[product.Url] = detail
+				mutex.Unlock()
+			}
+		}(product)
+	}
+	wg.Wait()
+
+	// description and image for every offer of the product
+	for i := range goods {
+		if detail, ok := details[goods[i].Url]; ok {
+			goods[i].Description = detail.Description
+			goods[i].LargeImageUrl = detail.LargeImageUrl
+		}
+	}
+
+	// offers missing in the search results, deduplicated later
+	added, upcoming := 0, 0
+	for _, product := range products {
+		detail, ok := details[product.Url]
+		if !ok {
+			continue
+		}
+		for _, g := range filterGoods(detail.Offers, filters[product.Query]) {
+			g.Description = detail.Description
+			g.LargeImageUrl = detail.LargeImageUrl
+			goods = append(goods, g)
+			added++
+			if g.Upcoming {
+				upcoming++
+			}
+		}
+	}
+
+	log.Printf("📄 detail pages: %d of %d products, %s%d%s offers (%d upcoming)", len(details), len(products), ColorBlue, added, ColorReset, upcoming)
+	return goods
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractDetailFromHtml(t *testing.T) {
+	file, err := os.Open("testdata/sleva-avivaz-azurit.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	doc, err := goquery.NewDocumentFromReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	product := Goods{Name: "Aviváž Azurit", Url: KOOPI_HOME_URL + "/sleva/avivaz-azurit", ScrapedAt: "20250817"}
+	detail := extractDetailFromHtml(doc, product)
+	if detail.Description != "Aviváž s vůní horské louky, 62 praní." {
+		t.Errorf("description = %q", detail.Description)
+	}
+	if detail.LargeImageUrl != KOOPI_IMAGE_URL+"/kupi/products/avivaz-azurit-large.jpg" {
+		t.Errorf("large image = %q", detail.LargeImageUrl)
+	}
+	if len(detail.Offers) != 2 {
+		t.Fatalf("%d offers, want 2", len(detail.Offers))
+	}
+	albert, kaufland := detail.Offers[0], detail.Offers[1]
+	if albert.Market != "Albert" || albert.PriceHal != 7990 || albert.Upcoming {
+		t.Errorf("first offer = %s %d upcoming %t, want Albert 7990 current", albert.Market, albert.PriceHal, albert.Upcoming)
+	}
+	if kaufland.Market != "Kaufland" || kaufland.PriceHal != 7490 || !kaufland.Upcoming || !kaufland.ClubCard {
+		t.Errorf("second offer = %s %d upcoming %t card %t, want Kaufland 7490 upcoming card", kaufland.Market, kaufland.PriceHal, kaufland.Upcoming, kaufland.ClubCard)
+	}
+}
+
+func TestDetailProducts(t *testing.T) {
+	queries := []ScrapeQuery{{Query: "aviváž"}, {Query: "prací gel"}}
+	goods := []Goods{
+		{Url: "/sleva/persil", Query: "prací gel"},
+		{Url: "/sleva/ariel", Query: "prací gel"},
+		{Url: "/sleva/ariel", Query: "prací gel"},
+		{Url: "/sleva/jar", Query: "/slevy/drogerie"}, // crawled section
+		{Url: "/sleva/lenor", Query: "aviváž"},
+		{Query: "aviváž"}, // no detail page
+	}
+	var urls []string
+	for _, p := range detailProducts(goods, queries) {
+		urls = append(urls, p.Url)
+	}
+	want := []string{"/sleva/lenor", "/sleva/ariel", "/sleva/persil", "/sleva/jar"}
+	if !slices.Equal(urls, want) {
+		t.Errorf("detailProducts = %q, want %q", urls, want)
+	}
+}
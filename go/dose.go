@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RegExps
+var (
+	// počet dávek / praní / tablet / kusů
+	reDoses = regexp.MustCompile(`(?i)(\d+)\s*(dávek|dávky|dávka|praní|pd|tablet\p{L}*|kapsl\p{L}*|ks)(?:[^\p{L}]|$)`)
+	// počet balení před počtem dávek ("2x 38 dávek")
+	reDosePacks = regexp.MustCompile(`(?i)(\d+)\s*[x×]$`)
+)
+
+// categories where "ks" means one wash
+var doseCategories = map[string]bool{
+	"PRANÍ": true,
+}
+
+// words before a count that turn it into a purchase condition
+var doseConditions = []string{
+	"zakoupení",
+	"koupi",
+	"nákupu",
+	"max.",
+	"max",
+	"od",
+}
+
+// findDoses - helper function to find dose count and pack count in text, ranges and purchase conditions are ignored
+func findDoses(s string, allowPieces bool) (int, int) {
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	best, bestPieces := 0, 0
+	packs, piecesPacks := 1, 1
+	for _, m := range reDoses.FindAllStringSubmatchIndex(s, -1) {
+		before := strings.TrimSpace(s[:m[0]])
+
+		// range like "47-59 dávek"
+		if strings.HasSuffix(before, "-") || strings.HasSuffix(before, "‑") || strings.HasSuffix(before, "–") {
+			continue
+		}
+
+		// purchase condition like "při zakoupení 2 ks"
+		words := strings.Fields(strings.ToLower(before))
+		if len(words) > 0 && isDoseCondition(words[len(words)-1]) {
+			continue
+		}
+
+		count, err := strconv.Atoi(s[m[2]:m[3]])
+		if err != nil || count <= 0 {
+			continue
+		}
+
+		// multipack like "2x 38 dávek"
+		countPacks := 1
+		if match := reDosePacks.FindStringSubmatch(before); len(match) == 2 {
+			if n, err := strconv.Atoi(match[1]); err == nil && n > 0 {
+				countPacks = n
+			}
+		}
+
+		if strings.EqualFold(s[m[4]:m[5]], "ks") {
+			if bestPieces == 0 {
+				bestPieces, piecesPacks = count, countPacks
+			}
+			continue
+		}
+		if best == 0 {
+			best, packs = count, countPacks
+		}
+	}
+	if best == 0 && allowPieces {
+		return bestPieces, piecesPacks
+	}
+	return best, packs
+}
+
+// isDoseCondition - helper function for doseConditions lookup
+func isDoseCondition(word string) bool {
+	for _, c := range doseConditions {
+		if word == c {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDoses - fill dose count and price per dose from Volume, Note and Name
+func parseDoses(g *Goods) {
+	g.Doses = 0
+	g.PricePerDoseHal = 0
+
+	doses := 0
+	if g.VolUnit == "dávka" && g.VolQty == g.VolMax {
+		doses = int(g.VolQty)
+	}
+	allowPieces := doseCategories[g.Category]
+	if doses == 0 {
+		count, packs := findDoses(g.Note, allowPieces)
+		if count == 0 {
+			count, packs = findDoses(g.Name, allowPieces)
+		}
+		// doses in the text are per pack of the multipack volume
+		if packs == 1 && g.VolPacks > 1 {
+			packs = g.VolPacks
+		}
+		doses = count * packs
+	}
+	if doses == 0 && allowPieces && g.VolUnit == "ks" && g.VolQty == g.VolMax {
+		doses = int(g.VolQty)
+	}
+	if doses == 0 || g.PriceHal == 0 {
+		return
+	}
+
+	g.Doses = doses
+	g.PricePerDoseHal = int(math.Round(float64(g.PriceHal) / float64(doses)))
+}
+
+// display symbols of currency codes
+var currencySymbols = map[string]string{
+	"CZK": "Kč",
+	"EUR": "€",
+}
+
+// formatHal - helper function to format haléře as a display price in the currency (CZK if unknown)
+func formatHal(amount int, currency string) string {
+	symbol, ok := currencySymbols[currency]
+	if !ok {
+		symbol = currencySymbols["CZK"]
+	}
+	return fmt.Sprintf("%d.%02d\u00a0%s", amount/100, amount%100, symbol)
+}
@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestFindDoses(t *testing.T) {
+	tests := []struct {
+		in          string
+		allowPieces bool
+		count       int
+		packs       int
+	}{
+		{"2x 38 dávek", false, 38, 2},
+		{"47-59 dávek", false, 0, 1},                  // range
+		{"akční cena při zakoupení 2 ks", true, 0, 1}, // purchase condition
+		{"kapsle 30 ks", false, 0, 1},
+		{"kapsle 30 ks", true, 30, 1},
+	}
+	for _, tt := range tests {
+		count, packs := findDoses(tt.in, tt.allowPieces)
+		if count != tt.count || packs != tt.packs {
+			t.Errorf("findDoses(%q, %v) = %d, %d, want %d, %d", tt.in, tt.allowPieces, count, packs, tt.count, tt.packs)
+		}
+	}
+}
+
+func TestParseDosesMultipack(t *testing.T) {
+	// doses of one pack in the name or note, packs in the name or volume
+	for _, g := range []Goods{
+		{Name: "Ariel gel 2x 38 dávek", Category: "PRANÍ", PriceHal: 38000},
+		{Name: "Lenor gel", Note: "38 dávek", Volume: "2x 1.9 l", Category: "PRANÍ", PriceHal: 38000},
+	} {
+		parseVolumes(&g)
+		parseDoses(&g)
+		if g.Doses != 76 || g.PricePerDoseHal != 500 {
+			t.Errorf("parseDoses(%q, %q) = %d doses, %d per dose, want 76, 500", g.Name, g.Volume, g.Doses, g.PricePerDoseHal)
+		}
+	}
+}
+
+func TestFormatHal(t *testing.T) {
+	if got := formatHal(505, "EUR"); got != "5.05\u00a0€" {
+		t.Errorf("formatHal(505, EUR) = %q", got)
+	}
+	if got := formatHal(1234, ""); got != "12.34\u00a0Kč" {
+		t.Errorf("formatHal(1234) = %q", got)
+	}
+}
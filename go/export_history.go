@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyDays - helper function to count days between two ISO dates
+func historyDays(from, to string) int {
+	f, t := parseIsoDate(from), parseIsoDate(to)
+	if f.IsZero() || t.IsZero() {
+		return 0
+	}
+	return int(t.Sub(f).Hours()/24 + 0.5)
+}
+
+// encodeSeries - delta encode sorted rows of one market into [market, day, price, +day, +price, ...]
+func encodeSeries(marketIdx int, rows []HistoryRow, start string) []int {
+	series := []int{marketIdx}
+	prevDay, prevPrice := 0, 0
+	for _, row := range rows {
+		day := historyDays(start, row.Date)
+		series = append(series, day-prevDay, row.PriceHal-prevPrice)
+		prevDay, prevPrice = day, row.PriceHal
+	}
+	return series
+}
+
+// exportHistory - save compact per-market price series of the goods on offer for the last OMNIBUS_LONG_DAYS days
+//
+//	{
+//	  "start": "2026-04-01",
+//	  "markets": ["BILLA", "dm drogerie", ...],
+//	  "series": {"<id>": [[market index, day, price, +day, +price, ...], ...]}
+//	}
+//
+// series are keyed by the permanent product ID, days are counted from start,
+// prices are in haléře, all values after the first pair are deltas
+func exportHistory(goods []Goods, filename string) {
+	// product hash -> market -> date -> row
+	products := make(map[string]map[string]map[string]HistoryRow)
+	now := time.Now()
+	last := ""
+	for _, g := range goods {
+		if g.PriceHal == 0 || g.Market == "" {
+			continue
+		}
+		if _, _, expired := validityState(g, now); expired {
+			continue
+		}
+		hash := productHash(g)
+		if products[hash] == nil {
+			products[hash] = make(map[string]map[string]HistoryRow)
+		}
+		if scraped, err := time.ParseInLocation(DATE_SCRAPED, g.ScrapedAt, time.Local); err == nil && scraped.Format(DATE_ISO) > last {
+			last = scraped.Format(DATE_ISO)
+		}
+	}
+
+	// fixed window before the latest scrape keeps the file small
+	cutoff := ""
+	if t := parseIsoDate(last); !t.IsZero() {
+		cutoff = t.AddDate(0, 0, -OMNIBUS_LONG_DAYS).Format(DATE_ISO)
+	}
+
+	// rows from the database
+	if db, err := openHistory(true); err == nil {
+		db.View(func(tx *bolt.Tx) error {
+			for hash := range products {
+				for _, row := range loadProductHistory(tx, hash) {
+					if row.Date < cutoff {
+						continue
+					}
+					if products[hash][row.Market] == nil {
+						products[hash][row.Market] = make(map[string]HistoryRow)
+					}
+					products[hash][row.Market][row.Date] = row
+				}
+			}
+			return nil
+		})
+		db.Close()
+	} else {
+		log.Printf("🫥 [%s] no price history: %v", config.Paths.HistoryDb, err)
+	}
+
+	// current offers (today is not in the database yet)
+	byDate := make(map[string][]Goods)
+	for _, g := range goods {
+		if scraped, err := time.ParseInLocation(DATE_SCRAPED, g.ScrapedAt, time.Local); err == nil {
+			date := scraped.Format(DATE_ISO)
+			byDate[date] = append(byDate[date], g)
+		}
+	}
+	for date, dayGoods := range byDate {
+		for hash, rows := range historyRowsFromGoods(dayGoods, date) {
+			if products[hash] == nil {
+				continue
+			}
+			for market, row := range rows {
+				if products[hash][market] == nil {
+					products[hash][market] = make(map[string]HistoryRow)
+				}
+				products[hash][market][date] = row
+			}
+		}
+	}
+
+	// market dictionary and start date
+	start := ""
+	marketSet := make(map[string]bool)
+	for _, markets := range products {
+		for market, dates := range markets {
+			marketSet[market] = true
+			for date := range dates {
+				if start == "" || date < start {
+					start = date
+				}
+			}
+		}
+	}
+	var markets []string
+	for market := range marketSet {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	marketIndex := make(map[string]int)
+	for i, market := range markets {
+		marketIndex[market] = i
+	}
+
+	// delta encoded series keyed by the permanent IDs
+	registry := loadIdRegistry(config.Paths.Ids)
+	series := make(map[int][][]int)
+	for hash, byMarket := range products {
+		id := registry.id(hash)
+		var marketNames []string
+		for market := range byMarket {
+			marketNames = append(marketNames, market)
+		}
+		sort.Strings(marketNames)
+		for _, market := range marketNames {
+			var rows []HistoryRow
+			for _, row := range byMarket[market] {
+				rows = append(rows, row)
+			}
+			sort.Slice(rows, func(i, j int) bool {
+				return rows[i].Date < rows[j].Date
+			})
+			series[id] = append(series[id], encodeSeries(marketIndex[market], rows, start))
+		}
+	}
+
+	registry.save(config.Paths.Ids)
+
+	outputData := make(map[string]any)
+	outputData["created"] = time.Now().Format(time.RFC3339)
+	outputData["start"] = start
+	outputData["markets"] = markets
+	outputData["series"] = series
+
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error opening for writing: %v", filename, err)
+	}
+	defer file.Close()
+	if err := json.NewEncoder(file).Encode(outputData); err != nil {
+		log.Fatalf("[%s] 💥 error writing to JSON: %v", filename, err)
+	}
+}
@@ -4,11 +4,13 @@ go 1.26.1
 
 require (
 	github.com/PuerkitoBio/goquery v1.10.3
+	github.com/andybalholm/cascadia v1.3.3
 	github.com/chai2010/webp v1.4.0
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/text v0.33.0
 )
 
 require (
-	github.com/andybalholm/cascadia v1.3.3 // indirect
 	golang.org/x/net v0.39.0 // indirect
+	golang.org/x/sys v0.32.0 // indirect
 )
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hashmap.json entry
+type HashmapEntry struct {
+	Id     int    `json:"id"`
+	Image  string `json:"image"`
+	Name   string `json:"name"`
+	Volume string `json:"volume"`
+	Seen   string `json:"seen"`
+}
+
+// exportImage - helper function to get the exported image name of the goods
+func exportImage(g Goods) string {
+	image := strings.TrimPrefix(g.ImageUrl, KOOPI_THUMBS_URL)
+	if image == "" {
+		return "default.webp"
+	}
+	return image
+}
+
+// loadHashmap - load existing hashmap.json, missing file means an empty hashmap
+func loadHashmap(filename string) (map[string]HashmapEntry, error) {
+	hashmap := make(map[string]HashmapEntry)
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return hashmap, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &hashmap); err != nil {
+		return nil, err
+	}
+	return hashmap, nil
+}
+
+// buildHashmap - merge stems into hashmap.json, only stems since the last seen day unless full is set
+func buildHashmap(full bool) error {
+	hashmap := make(map[string]HashmapEntry)
+	if !full {
+		var err error
+		if hashmap, err = loadHashmap(config.Paths.Hashmap); err != nil {
+			return err
+		}
+	}
+
+	// last seen day
+	lastSeen := ""
+	for _, entry := range hashmap {
+		if entry.Seen > lastSeen {
+			lastSeen = entry.Seen
+		}
+	}
+
+	files, dates, err := listStems(config.Paths.Stems)
+	if err != nil {
+		return err
+	}
+
+	registry := loadIdRegistry(config.Paths.Ids)
+	processed := 0
+	conflicts := make(map[string]bool)
+	for _, file := range files {
+		date := dates[file]
+		if date < lastSeen {
+			continue
+		}
+		data, err := readExportedData(filepath.Join(config.Paths.Stems, file))
+		if err != nil {
+			log.Printf("[%s] 💥 error loading: %v", file, err)
+			continue
+		}
+		if len(data.IdHashmap) == 0 {
+			log.Printf("[%s] 🫥 no idhashmap, product hashes are computed", file)
+		}
+		for _, g := range data.goods() {
+			// the hash stored with the item, computed only for old stems
+			hash := productHash(g)
+			entry := HashmapEntry{
+				Id:     registry.id(hash),
+				Image:  exportImage(g),
+				Name:   g.Name,
+				Volume: g.Volume,
+				Seen:   date,
+			}
+			if old, ok := hashmap[hash]; ok {
+				if old.Name != entry.Name {
+					conflicts[fmt.Sprintf("%s %s name: %q → %q", date, hash, old.Name, entry.Name)] = true
+				}
+				if old.Volume != entry.Volume {
+					conflicts[fmt.Sprintf("%s %s volume: %q → %q", date, hash, old.Volume, entry.Volume)] = true
+				}
+				if old.Image != entry.Image && entry.Image != "default.webp" && old.Image != "default.webp" {
+					conflicts[fmt.Sprintf("%s %s image: %s → %s", date, hash, old.Image, entry.Image)] = true
+				}
+				// keep the known image instead of the placeholder
+				if entry.Image == "default.webp" {
+					entry.Image = old.Image
+				}
+			}
+			hashmap[hash] = entry
+		}
+		processed++
+	}
+	registry.save(config.Paths.Ids)
+
+	// conflicts
+	var sorted []string
+	for c := range conflicts {
+		sorted = append(sorted, c)
+	}
+	sort.Strings(sorted)
+	for _, c := range sorted {
+		fmt.Printf("⚠️ %s\n", c)
+	}
+
+	// map keys are sorted by the encoder
+	content, err := json.Marshal(hashmap)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(config.Paths.Hashmap, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n🗺️ Hashmap: %d stems processed, %d conflicts, %d unique items saved to %s.\n\n", processed, len(conflicts), len(hashmap), config.Paths.Hashmap)
+	return nil
+}
+
+// hashmapCommand - hashmap command
+func hashmapCommand(args []string) {
+	full := len(args) > 0 && strings.TrimLeft(args[0], "-") == "full"
+	if err := buildHashmap(full); err != nil {
+		log.Fatalf("[%s] 💥 error building hashmap: %v", config.Paths.Hashmap, err)
+	}
+}
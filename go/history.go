@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// bolt buckets
+var (
+	bucketPrices = []byte("prices") // product hash -> "market|date" -> HistoryRow
+	bucketStems  = []byte("stems")  // stem file name -> StemRecord
+)
+
+// RegExps
+var (
+	// stems/data_YYYY-MM-DD.json
+	reStemFile = regexp.MustCompile(`^data_(\d{4}-\d{2}-\d{2})\.json$`)
+)
+
+// one price record of a product in a market on a given day
+type HistoryRow struct {
+	Date            string `json:"date"`
+	Market          string `json:"market"`
+	PriceHal        int    `json:"price_hal"`
+	PricePerUnitHal int    `json:"ppunit_hal,omitempty"`
+	DiscountPct     int    `json:"discount_pct,omitempty"`
+	Club            string `json:"club,omitempty"`
+}
+
+// ingested stem file, a changed checksum means the stem was rewritten
+type StemRecord struct {
+	Rows int    `json:"rows"`
+	Md5  string `json:"md5"`
+}
+
+// productHash - stable product hash (Name+Volume+Category+SubCat), the stored one survives overrides
+func productHash(g Goods) string {
+	if g.Hash != "" {
+		return g.Hash
+	}
+	hash := md5.Sum([]byte(g.Name + g.Volume + g.Category + g.SubCat))
+	return hex.EncodeToString(hash[:])
+}
+
+// historyKey - helper function to build the row key inside the product bucket
+func historyKey(market, date string) []byte {
+	return []byte(market + "|" + date)
+}
+
+// listStems - list stem files with their dates, sorted by date
+func listStems(dir string) ([]string, map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []string
+	dates := make(map[string]string)
+	for _, e := range entries {
+		match := reStemFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || len(match) < 2 {
+			continue
+		}
+		files = append(files, e.Name())
+		dates[e.Name()] = match[1]
+	}
+	sort.Strings(files)
+	return files, dates, nil
+}
+
+// openHistory - open the history database
+func openHistory(readOnly bool) (*bolt.DB, error) {
+	if readOnly {
+		if _, err := os.Stat(config.Paths.HistoryDb); err != nil {
+			return nil, err
+		}
+	}
+	return bolt.Open(config.Paths.HistoryDb, 0644, &bolt.Options{ReadOnly: readOnly, Timeout: time.Second})
+}
+
+// historyRowsFromGoods - helper function to reduce the stem to one row per product, market and day
+func historyRowsFromGoods(goods []Goods, date string) map[string]map[string]HistoryRow {
+	rows := make(map[string]map[string]HistoryRow)
+	for _, g := range goods {
+		// upcoming offers are not the price of the day
+		if g.Market == "" || g.PriceHal == 0 || g.Upcoming {
+			continue
+		}
+		hash := productHash(g)
+		if rows[hash] == nil {
+			rows[hash] = make(map[string]HistoryRow)
+		}
+		// keep the lowest price of the day
+		if old, ok := rows[hash][g.Market]; ok && old.PriceHal <= g.PriceHal {
+			continue
+		}
+		rows[hash][g.Market] = HistoryRow{
+			Date:            date,
+			Market:          g.Market,
+			PriceHal:        g.PriceHal,
+			PricePerUnitHal: g.PricePerUnitHal,
+			DiscountPct:     g.DiscountPct,
+			Club:            g.Club,
+		}
+	}
+	return rows
+}
+
+// stemChecksum - helper function to get md5 of the stem file
+func stemChecksum(file string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(config.Paths.Stems, file))
+	if err != nil {
+		return "", err
+	}
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// deleteHistoryDate - helper function to remove all rows of the day before the stem is ingested again
+func deleteHistoryDate(prices *bolt.Bucket, date string) error {
+	suffix := "|" + date
+	return prices.ForEachBucket(func(hash []byte) error {
+		product := prices.Bucket(hash)
+		var keys [][]byte
+		product.ForEach(func(k, v []byte) error {
+			if strings.HasSuffix(string(k), suffix) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range keys {
+			if err := product.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ingestStem - store one stem file into the database, rows of a rewritten stem are replaced
+func ingestStem(db *bolt.DB, file string, date string, checksum string, replace bool) (int, error) {
+	goods, err := loadGoodsFromJson(filepath.Join(config.Paths.Stems, file))
+	if err != nil {
+		return 0, err
+	}
+	rows := historyRowsFromGoods(goods, date)
+
+	count := 0
+	err = db.Update(func(tx *bolt.Tx) error {
+		prices, err := tx.CreateBucketIfNotExists(bucketPrices)
+		if err != nil {
+			return err
+		}
+		if replace {
+			if err := deleteHistoryDate(prices, date); err != nil {
+				return err
+			}
+		}
+		for hash, markets := range rows {
+			product, err := prices.CreateBucketIfNotExists([]byte(hash))
+			if err != nil {
+				return err
+			}
+			for market, row := range markets {
+				value, err := json.Marshal(row)
+				if err != nil {
+					return err
+				}
+				if err := product.Put(historyKey(market, date), value); err != nil {
+					return err
+				}
+				count++
+			}
+		}
+		stems, err := tx.CreateBucketIfNotExists(bucketStems)
+		if err != nil {
+			return err
+		}
+		record, err := json.Marshal(StemRecord{Rows: count, Md5: checksum})
+		if err != nil {
+			return err
+		}
+		return stems.Put([]byte(file), record)
+	})
+	return count, err
+}
+
+// ingestHistory - load stems into the history database, only new or changed stems unless full is set
+func ingestHistory(full bool) error {
+	files, dates, err := listStems(config.Paths.Stems)
+	if err != nil {
+		return err
+	}
+
+	db, err := openHistory(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if full {
+		err = db.Update(func(tx *bolt.Tx) error {
+			for _, name := range [][]byte{bucketPrices, bucketStems} {
+				if err := tx.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// stems already ingested, old records without checksum are ingested again
+	done := make(map[string]StemRecord)
+	err = db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketStems)
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var record StemRecord
+			json.Unmarshal(v, &record)
+			done[string(k)] = record
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	added, replaced := 0, 0
+	for _, file := range files {
+		checksum, err := stemChecksum(file)
+		if err != nil {
+			log.Printf("[%s] 💥 error reading: %v", file, err)
+			continue
+		}
+		record, ok := done[file]
+		if ok && record.Md5 == checksum {
+			continue
+		}
+		count, err := ingestStem(db, file, dates[file], checksum, ok)
+		if err != nil {
+			log.Printf("[%s] 💥 error ingesting: %v", file, err)
+			continue
+		}
+		if ok {
+			log.Printf("🗃️ %s %s+%d%s (changed, %d rows replaced)", file, ColorBlue, count, ColorReset, record.Rows)
+			replaced++
+			continue
+		}
+		log.Printf("🗃️ %s %s+%d%s", file, ColorBlue, count, ColorReset)
+		added++
+	}
+
+	fmt.Printf("\n🗄️ History: %d stems total, %d new, %d changed, saved to %s.\n\n", len(files), added, replaced, config.Paths.HistoryDb)
+	return nil
+}
+
+// loadProductHistory - read all history rows of the product, sorted by market and date
+func loadProductHistory(tx *bolt.Tx, hash string) []HistoryRow {
+	prices := tx.Bucket(bucketPrices)
+	if prices == nil {
+		return nil
+	}
+	product := prices.Bucket([]byte(hash))
+	if product == nil {
+		return nil
+	}
+	var rows []HistoryRow
+	product.ForEach(func(k, v []byte) error {
+		var row HistoryRow
+		if err := json.Unmarshal(v, &row); err == nil {
+			rows = append(rows, row)
+		}
+		return nil
+	})
+	return rows
+}
+
+// historyCommand - history command
+func historyCommand(args []string) {
+	full := len(args) > 0 && strings.TrimLeft(args[0], "-") == "full"
+	if err := ingestHistory(full); err != nil {
+		log.Fatalf("[%s] 💥 error building history: %v", config.Paths.HistoryDb, err)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// persisted map of product hashes to permanent integer IDs
+type IdRegistry struct {
+	ids   map[string]int
+	next  int
+	added int
+}
+
+// loadIdRegistry - load the registry, missing file means an empty registry
+func loadIdRegistry(filename string) *IdRegistry {
+	registry := &IdRegistry{ids: make(map[string]int), next: 1}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Fatalf("[%s] 💥 error reading: %v", filename, err)
+		}
+		return registry
+	}
+	if err := json.Unmarshal(content, &registry.ids); err != nil {
+		log.Fatalf("[%s] 💥 error parsing: %v", filename, err)
+	}
+	for _, id := range registry.ids {
+		if id >= registry.next {
+			registry.next = id + 1
+		}
+	}
+	return registry
+}
+
+// id - permanent ID of the product hash, new products get the next free ID
+func (r *IdRegistry) id(hash string) int {
+	if id, ok := r.ids[hash]; ok {
+		return id
+	}
+	id := r.next
+	r.ids[hash] = id
+	r.next++
+	r.added++
+	return id
+}
+
+// save - save the registry if anything was added
+func (r *IdRegistry) save(filename string) {
+	if r.added == 0 {
+		return
+	}
+	content, err := json.MarshalIndent(r.ids, "", " ")
+	if err != nil {
+		log.Fatalf("[%s] 💥 error encoding: %v", filename, err)
+	}
+	if err := os.WriteFile(filename, append(content, '\n'), 0644); err != nil {
+		log.Fatalf("[%s] 💥 error writing: %v", filename, err)
+	}
+	log.Printf("🆔 %d new product IDs saved to %s (%d total)", r.added, filename, len(r.ids))
+	r.added = 0
+}
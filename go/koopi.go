@@ -3,16 +3,13 @@ package main
 import (
 	"bytes"
 	"context"
-	"crypto/md5"
 	"encoding/csv"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -34,15 +31,9 @@ import (
 )
 
 const (
-	HTML_CACHE  = "../cache"
-	IMAGE_CACHE = "../images"
-
-	INPUT_CSV   = "scrape.csv"
-	OUTPUT_CSV  = "koopi.csv"
-	OUTPUT_JSON = "koopi.json"
-
 	KOOPI_HOME_URL   = "https://www.kupi.cz"
 	KOOPI_IMAGE_URL  = "https://img.kupi.cz"
+	KOOPI_THUMBS_URL = KOOPI_IMAGE_URL + "/kupi/thumbs/"
 	KOOPI_SEARCH_URL = "https://www.kupi.cz/hledej?f="
 	KOOPI_SUBPAGE    = "&page="
 
@@ -56,35 +47,6 @@ const (
 	REQ_TIMEOUT       = 17 * time.Second
 )
 
-// UA strings
-var UserAgents = []string{
-	"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 10; LM-Q720) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 11; CPH2251) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 12; SM-A525F) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 12; V2134) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 13; M2101K6G) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 13; SM-G991U) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/117.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 13; SM-S908E) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Linux; Android 14; Pixel 8 Pro) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Mobile Safari/537.36",
-	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.6045.159 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/78.0.3904.108 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/80.0.3987.149 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.85 Safari/537.36",
-	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/94.0.4606.81 Safari/537.36",
-	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36",
-	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.6167.85 Safari/537.36",
-	"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:120.0.1) Gecko/20100101 Firefox/120.0.1",
-	"Mozilla/5.0 (iPad; CPU OS 16_7_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
-	"Mozilla/5.0 (iPad; CPU OS 17_0_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0.1 Mobile/15E148 Safari/604.1",
-	"Mozilla/5.0 (iPhone; CPU iPhone OS 13_2_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0.3 Mobile/15E148 Safari/604.1",
-	"Mozilla/5.0 (iPhone; CPU iPhone OS 15_7_9 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.6.5 Mobile/15E148 Safari/604.1",
-	"Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) FxiOS/118.0 Mobile/15E148 Safari/605.1.15",
-	"Mozilla/5.0 (iPhone; CPU iPhone OS 16_6_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
-	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0.1 Mobile/15E148 Safari/604.1",
-}
-
 // colors
 const (
 	ColorReset  = "\033[0m"
@@ -112,109 +74,20 @@ var (
 	rePreps = regexp.MustCompile(`(?i)(^|[\s])([svzkaiou])\s+`)
 	// číslo + mezera + jednotka
 	reUnits = regexp.MustCompile(`(\d+)\s+(g|kg|ml|l|ks)\b`)
-	// datum v budoucnosti
-	reFutureDate = regexp.MustCompile(`(\d{1,2})\.\s*(\d{1,2})\.`)
 	// non-alphanumeric
 	nonAlphanumeric = regexp.MustCompile("[^a-z0-9]+")
 	// bones
 	regaz = regexp.MustCompile(`[^a-z\s]+`)
 )
 
-// markets to ignore
-var blockedMarkets = []string{
-	"auto kelly",
-	"benu lékárna",
-	"tesco",
-	"albert",
-}
-
-// product names to ignore
-var blockedGoods = []string{
-	"jarmark",
-	"jarmex",
-	"parkside",
-	"pikok",
-	"pilos",
-	"stone master",
-
-	"aditivum do benzínu",
-	"aditivum zimní",
-	"benzínová pila",
-	"bez lepku",
-	"bonboniéra",
-	"bonbony",
-	"matrace",
-	"brambůrky",
-	"cibule",
-	"cibulka",
-	"džus",
-	"fazole",
-	"gril",
-	"hrášek",
-	"jogurt",
-	"játra",
-	"kečup",
-	"kniha",
-	"kompot",
-	"kukuřice",
-	"kuře",
-	"kuřecí",
-	"káva",
-	"křovinořez",
-	"křupky",
-	"limonáda",
-	"masturbátor",
-	"mléko čerstvé",
-	"mléčná",
-	"nápoj",
-	"olivy",
-	"omáčky",
-	"oplatka",
-	"opunciový",
-	"ovocné",
-	"panenka",
-	"papír",
-	"piškoty",
-	"podlaha",
-	"poleva",
-	"pomazánka",
-	"pomazánkové",
-	"pro koně",
-	"protein",
-	"puding",
-	"přesnídávka",
-	"rohlík",
-	"rýžová",
-	"rýžový",
-	"salát",
-	"sekačka",
-	"shiitake",
-	"skořicové",
-	"slanina",
-	"smetana",
-	"smetanový",
-	"stěna",
-	"svačinka",
-	"sýr",
-	"tvaroh",
-	"těstoviny",
-	"vibrátor",
-	"vodka",
-	"vína",
-	"víno",
-	"vířivka",
-	"zeleninová",
-	"závitky",
-	"čaj",
-	"čokoládové",
-	"řepa",
-	"řetězová",
-	"řízky",
-	"šunka",
-	"šťáva",
-	"žampiony",
-	"žervé",
-	"židle",
+// scrape.csv page to scrape
+type ScrapeTask struct {
+	url      string
+	cacheKey string
+	category string
+	query    string
+	page     int
+	filter   QueryFilter
 }
 
 // product structure
@@ -234,6 +107,45 @@ type Goods struct {
 	ImageUrl     string
 	SubCat       string
 	ScrapedAt    string
+
+	// parsed values
+	PriceHal          int
+	Currency          string
+	PricePerUnitHal   int
+	PricePerUnitUnit  string
+	DiscountPct       int
+	VolQty            float64
+	VolMax            float64
+	VolUnit           string
+	VolPacks          int
+	VolPiece          float64
+	Doses             int
+	PricePerDoseHal   int
+	ValidFrom         string
+	ValidTo           string
+	Low30Hal          int
+	Low90Hal          int
+	FakeDiscount      bool
+	SuggestedCat      string
+	CatConfidence     float64
+	CatSource         string // how the category was assigned (CAT_SOURCE_*)
+	Pinned            bool
+	ClubProgram       string
+	ClubMember        bool
+	ClubApp           bool
+	ClubCard          bool
+	PurchaseLimit     int
+	PromoType         string
+	PromoMinQty       int
+	EffectivePriceHal int
+	OriginalPrice     string
+	OriginalPriceHal  int
+	OriginalDerived   bool
+	SuspiciousPrice   bool
+	Upcoming          bool
+	Description       string
+	LargeImageUrl     string
+	Hash              string // product hash before the manual overrides
 }
 
 // getBone - helper function to get string bones
@@ -359,17 +271,6 @@ func isProcessRunning(pid int) bool {
 	return syscall.Kill(pid, syscall.Signal(0)) == nil
 }
 
-// isForbidden - helper function to check if product name contains forbidden strings
-func isForbidden(name string, forbidden []string) bool {
-	lowerName := strings.ToLower(name)
-	for _, s := range forbidden {
-		if strings.Contains(lowerName, s) {
-			return true
-		}
-	}
-	return false
-}
-
 // extractGoodsFromHtml - extract data from HTML
 func extractGoodsFromHtml(doc *goquery.Document, category string, query string, scrapedAt string) []Goods {
 	var goods []Goods
@@ -386,7 +287,7 @@ func extractGoodsFromHtml(doc *goquery.Document, category string, query string,
 		productName = sanitizeString(productName)
 
 		// skip forbidden goods
-		if isForbidden(productName, blockedGoods) {
+		if isForbidden(productName, "name", Goods{Name: productName, Category: category, Query: query}, config.BlockedGoods) {
 			return
 		}
 
@@ -402,106 +303,17 @@ func extractGoodsFromHtml(doc *goquery.Document, category string, query string,
 		}
 
 		// iterate through each specific offer within the product group
+		product := Goods{
+			Category:  category,
+			Query:     query,
+			ScrapedAt: scrapedAt,
+			Name:      productName,
+			Url:       productUrl,
+			ImageUrl:  productImageUrl,
+		}
 		s.Find(".discount_row").Each(func(j int, offer *goquery.Selection) {
-			var newGoods Goods
-			newGoods.Category = category
-			newGoods.Query = query
-			newGoods.ScrapedAt = scrapedAt
-			newGoods.Name = productName
-			newGoods.Url = productUrl
-			newGoods.ImageUrl = productImageUrl
-
-			// name
-			newGoods.Name = strings.ReplaceAll(newGoods.Name, "-", "\u2011")
-			newGoods.Name = strings.ReplaceAll(newGoods.Name, "Wet n Wild", "Wet&Wild")
-
-			// price
-			newGoods.Price = strings.TrimSpace(offer.Find(".discount_price_value").Text())
-			newGoods.Price = strings.ReplaceAll(newGoods.Price, ",", ".")
-
-			// price per unit
-			newGoods.PricePerUnit = strings.TrimSpace(offer.Find(".price_per_unit").Text())
-			newGoods.PricePerUnit = strings.ReplaceAll(newGoods.PricePerUnit, ",", ".")
-
-			// discount
-			newGoods.Discount = strings.TrimSpace(offer.Find(".discount_percentage").Text())
-			newGoods.Discount = strings.ReplaceAll(newGoods.Discount, "–", "-")
-			newGoods.Discount = strings.TrimSpace(newGoods.Discount)
-
-			// volume
-			newGoods.Volume = strings.TrimSpace(offer.Find(".discount_amount").Text())
-			newGoods.Volume = strings.TrimPrefix(newGoods.Volume, "/")
-			newGoods.Volume = strings.TrimSpace(newGoods.Volume)
-
-			// note
-			newGoods.Note = strings.TrimSpace(offer.Find(".discount_note").Text())
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "vybrané druhy", "různé druhy")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "láhev", "lahev")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "láhve", "lahve")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " 250g", " 250 g")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " 340g", " 340 g")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " 500g", " 500 g")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "max ", "max. ")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "pet lahev", "PET lahev")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "1 + 1", "1+1")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "4 + 2", "4+2")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " + ", " +")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " & ", "&")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " - ", "-")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, "-", "\u2011")
-			newGoods.Note = strings.ReplaceAll(newGoods.Note, " \u0026 ", "\u0026")
-			newGoods.Note = sanitizeString(newGoods.Note)
-			newGoods.Note = typoFix(newGoods.Note)
-			// skip forbidden goods
-			if isForbidden(newGoods.Note, blockedGoods) {
-				newGoods.Name = ""
-			}
-
-			// club
-			newGoods.Club = strings.TrimSpace(offer.Find(".discounts_club").Text())
-			newGoods.Club = strings.ToLower(newGoods.Club)
-			newGoods.Club = strings.ReplaceAll(newGoods.Club, "platí pro členy klubu", "pro členy klubu")
-			newGoods.Club = strings.ReplaceAll(newGoods.Club, "cena s aplikací lidl plus", "aplikace Lidl Plus 📱")
-			newGoods.Club = strings.ReplaceAll(newGoods.Club, "cena s kaufland card", "Kaufland Card 💳️")
-			newGoods.Club = sanitizeString(newGoods.Club)
-
-			// validity
-			newGoods.Validity = strings.TrimSpace(offer.Find(".discounts_validity").Text())
-			newGoods.Validity = sanitizeString(newGoods.Validity)
-
-			// market
-			newGoods.Market = strings.TrimSpace(offer.Find(".discounts_shop_name a span").Text())
-			newGoods.Market = strings.ReplaceAll(newGoods.Market, "&", "and")
-			newGoods.Market = sanitizeString(newGoods.Market)
-
-			// skip forbidden markets
-			if isForbidden(newGoods.Market, blockedMarkets) {
-				return
-			}
-
-			// add SubCat based on Note
-			if strings.Contains(newGoods.Note, "zálohovaná lahev") {
-				newGoods.SubCat = "lahev"
-			}
-			if strings.Contains(newGoods.Note, "plech") {
-				newGoods.SubCat = "plech"
-			}
-
-			// function helper to compare prices
-			cleanForCompare := func(s string) string {
-				s = strings.ReplaceAll(s, "\u00a0", "") // remove #A0s
-				s = strings.ReplaceAll(s, " ", "")      // remove spaces
-				s = strings.ToLower(s)
-				return s
-			}
-
-			fullPrice := fmt.Sprintf("%s/%s", newGoods.Price, newGoods.Volume)
-			if cleanForCompare(fullPrice) == cleanForCompare(newGoods.PricePerUnit) {
-				newGoods.PricePerUnit = "\u00a0"
-			}
-
 			// append the struct to the global list
-			if newGoods.Name != "" {
+			if newGoods, ok := extractOffer(offer, product); ok {
 				goods = append(goods, newGoods)
 			}
 		})
@@ -510,16 +322,108 @@ func extractGoodsFromHtml(doc *goquery.Document, category string, query string,
 	return goods
 }
 
+// extractOffer - extract one market offer of the product, false if the offer is skipped
+func extractOffer(offer *goquery.Selection, product Goods) (Goods, bool) {
+	newGoods := product
+
+	// name
+	newGoods.Name = applyRewrites(newGoods.Name, config.NameRewrites)
+
+	// price
+	newGoods.Price = strings.TrimSpace(offer.Find(".discount_price_value").Text())
+	newGoods.Price = strings.ReplaceAll(newGoods.Price, ",", ".")
+
+	// original price, only with a selector checked against the listing
+	if config.OriginalPriceSelector != "" {
+		newGoods.OriginalPrice = strings.TrimSpace(offer.Find(config.OriginalPriceSelector).First().Text())
+		newGoods.OriginalPrice = strings.ReplaceAll(newGoods.OriginalPrice, ",", ".")
+	}
+
+	// price per unit
+	newGoods.PricePerUnit = strings.TrimSpace(offer.Find(".price_per_unit").Text())
+	newGoods.PricePerUnit = strings.ReplaceAll(newGoods.PricePerUnit, ",", ".")
+
+	// discount
+	newGoods.Discount = strings.TrimSpace(offer.Find(".discount_percentage").Text())
+	newGoods.Discount = strings.ReplaceAll(newGoods.Discount, "–", "-")
+	newGoods.Discount = strings.TrimSpace(newGoods.Discount)
+
+	// volume
+	newGoods.Volume = strings.TrimSpace(offer.Find(".discount_amount").Text())
+	newGoods.Volume = strings.TrimPrefix(newGoods.Volume, "/")
+	newGoods.Volume = strings.TrimSpace(newGoods.Volume)
+
+	// note
+	newGoods.Note = strings.TrimSpace(offer.Find(".discount_note").Text())
+	newGoods.Note = applyRewrites(newGoods.Note, config.NoteRewrites)
+	newGoods.Note = sanitizeString(newGoods.Note)
+	newGoods.Note = typoFix(newGoods.Note)
+	// skip forbidden goods
+	if isForbidden(newGoods.Note, "note", newGoods, config.BlockedGoods) {
+		newGoods.Name = ""
+	}
+
+	// club
+	newGoods.Club = strings.TrimSpace(offer.Find(".discounts_club").Text())
+	newGoods.Club = strings.ToLower(newGoods.Club)
+	newGoods.Club = applyRewrites(newGoods.Club, config.ClubRewrites)
+	newGoods.Club = sanitizeString(newGoods.Club)
+
+	// validity
+	newGoods.Validity = strings.TrimSpace(offer.Find(".discounts_validity").Text())
+	newGoods.Validity = sanitizeString(newGoods.Validity)
+	parseValidityFields(&newGoods)
+
+	// market
+	newGoods.Market = strings.TrimSpace(offer.Find(".discounts_shop_name a span").Text())
+	newGoods.Market = strings.ReplaceAll(newGoods.Market, "&", "and")
+	newGoods.Market = sanitizeString(newGoods.Market)
+
+	// skip forbidden markets
+	if isForbidden(newGoods.Market, "market", newGoods, config.BlockedMarkets) {
+		return newGoods, false
+	}
+
+	// add SubCat based on Note
+	if strings.Contains(newGoods.Note, "zálohovaná lahev") {
+		newGoods.SubCat = "lahev"
+	}
+	if strings.Contains(newGoods.Note, "plech") {
+		newGoods.SubCat = "plech"
+	}
+
+	// typed prices, volumes, promotions and club condition
+	parsePrices(&newGoods)
+	parseVolumes(&newGoods)
+	parsePromotion(&newGoods)
+	parseClub(&newGoods)
+
+	// function helper to compare prices
+	cleanForCompare := func(s string) string {
+		s = strings.ReplaceAll(s, "\u00a0", "") // remove #A0s
+		s = strings.ReplaceAll(s, " ", "")      // remove spaces
+		s = strings.ToLower(s)
+		return s
+	}
+
+	fullPrice := fmt.Sprintf("%s/%s", newGoods.Price, newGoods.Volume)
+	if cleanForCompare(fullPrice) == cleanForCompare(newGoods.PricePerUnit) {
+		newGoods.PricePerUnit = "\u00a0"
+	}
+
+	return newGoods, newGoods.Name != ""
+}
+
 // saveHtmlToCache - save HTML to cache
 func saveHtmlToCache(cacheName string, content []byte) {
-	if _, err := os.Stat(HTML_CACHE); os.IsNotExist(err) {
-		err = os.MkdirAll(HTML_CACHE, 0755)
+	if _, err := os.Stat(config.Paths.HtmlCache); os.IsNotExist(err) {
+		err = os.MkdirAll(config.Paths.HtmlCache, 0755)
 		if err != nil {
-			log.Printf("[%s] 💥 error creating cache folder [%s]: %v", cacheName, HTML_CACHE, err)
+			log.Printf("[%s] 💥 error creating cache folder [%s]: %v", cacheName, config.Paths.HtmlCache, err)
 			return
 		}
 	}
-	filePath := filepath.Join(HTML_CACHE, cacheName)
+	filePath := filepath.Join(config.Paths.HtmlCache, cacheName)
 	err := os.WriteFile(filePath, content, 0644)
 	if err != nil {
 		log.Printf("[%s] 💥 error saving to cache: %v", cacheName, err)
@@ -528,7 +432,7 @@ func saveHtmlToCache(cacheName string, content []byte) {
 
 // loadHtmlFromCache - load HTML from cache
 func loadHtmlFromCache(cacheName string) (*goquery.Document, error) {
-	filePath := filepath.Join(HTML_CACHE, cacheName)
+	filePath := filepath.Join(config.Paths.HtmlCache, cacheName)
 	localFileContent, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
@@ -543,16 +447,16 @@ func loadHtmlFromCache(cacheName string) (*goquery.Document, error) {
 
 // saveImageToCache - save image to cache for WebP processing
 func saveImageToCache(imageUrl string) {
-	if _, err := os.Stat(IMAGE_CACHE); os.IsNotExist(err) {
-		err = os.MkdirAll(IMAGE_CACHE, 0755)
+	if _, err := os.Stat(config.Paths.ImageCache); os.IsNotExist(err) {
+		err = os.MkdirAll(config.Paths.ImageCache, 0755)
 		if err != nil {
-			log.Printf("[%s] 💥 error creating image cache folder: %v", IMAGE_CACHE, err)
+			log.Printf("[%s] 💥 error creating image cache folder: %v", config.Paths.ImageCache, err)
 			return
 		}
 	}
 
 	fileName := filepath.Base(imageUrl)
-	filePath := filepath.Join(IMAGE_CACHE, fileName)
+	filePath := filepath.Join(config.Paths.ImageCache, fileName)
 	if _, err := os.Stat(filePath); err == nil {
 		return
 	}
@@ -581,18 +485,16 @@ func saveImageToCache(imageUrl string) {
 	}
 }
 
-// scrapePage - scrape pages (cache/online)
-func scrapePage(UA string, ctx context.Context, urlToScrape string, cacheName string, category string, query string, allGoods *[]Goods, mutex *sync.Mutex, wg *sync.WaitGroup) {
-	defer wg.Done()
-
+// scrapePage - scrape pages (cache/online), returns the last result page seen (0 if unknown)
+func scrapePage(UA string, ctx context.Context, urlToScrape string, cacheName string, category string, query string, page int, filter QueryFilter, allGoods *[]Goods, mutex *sync.Mutex) int {
 	// 1. try cache first
 	doc, err := loadHtmlFromCache(cacheName)
 	if err == nil {
-		scrapedAt := time.Now().Format("20060102")
-		if info, err := os.Stat(filepath.Join(HTML_CACHE, cacheName)); err == nil {
-			scrapedAt = info.ModTime().Format("20060102")
+		scrapedAt := time.Now().Format(DATE_SCRAPED)
+		if info, err := os.Stat(filepath.Join(config.Paths.HtmlCache, cacheName)); err == nil {
+			scrapedAt = info.ModTime().Format(DATE_SCRAPED)
 		}
-		goodsList := extractGoodsFromHtml(doc, category, query, scrapedAt)
+		goodsList := filterGoods(extractGoodsFromHtml(doc, category, query, scrapedAt), filter)
 		mutex.Lock()
 		for _, good := range goodsList {
 			saveImageToCache(good.ImageUrl)
@@ -606,14 +508,52 @@ func scrapePage(UA string, ctx context.Context, urlToScrape string, cacheName st
 		} else {
 			log.Printf("📦 %d %s %s+%d%s", len(*allGoods), query, ColorBlue, len(goodsList), ColorReset)
 		}
-		return
+		return discoverPages(doc, page)
 	}
 
-	// 2. Rate Limiter Acquisition (Only for network scrape)
+	// 2. network scrape
+	bodyBytes := fetchPage(UA, ctx, urlToScrape, query)
+	if bodyBytes == nil {
+		return 0
+	}
+	resDoc, err := goquery.NewDocumentFromReader(bytes.NewReader(bodyBytes))
+	if err != nil {
+		log.Printf("[%s] 😵‍💫 error creating document: %v", query, err)
+		return 0
+	}
+
+	// extract goods from HTML
+	scrapedAt := time.Now().Format(DATE_SCRAPED)
+	goodsList := filterGoods(extractGoodsFromHtml(resDoc, category, query, scrapedAt), filter)
+
+	// save HTML to cache
+	saveHtmlToCache(cacheName, bodyBytes)
+
+	// extract goods images
+	mutex.Lock()
+	for _, good := range goodsList {
+		saveImageToCache(good.ImageUrl)
+	}
+	*allGoods = append(*allGoods, goodsList...)
+	total := len(*allGoods)
+	mutex.Unlock()
+
+	// console
+	if total == 0 {
+		log.Printf("🫥 %d %s %s0%s%s%s", total, query, ColorBlue, ColorCyan, urlToScrape, ColorReset)
+	} else {
+		log.Printf("📦 %d %s %s+%d%s", total, query, ColorBlue, len(goodsList), ColorReset)
+	}
+	return discoverPages(resDoc, page)
+}
+
+// fetchPage - download the page with the shared rate limiter, nil if cancelled or failed
+func fetchPage(UA string, ctx context.Context, urlToScrape string, label string) []byte {
+	// Rate Limiter Acquisition (Only for network scrape)
 	select {
 	case <-ctx.Done():
 		// Task cancelled before acquiring token
-		return
+		return nil
 	case <-rateLimiter:
 		defer func() {
 			// A. Calculate sleep time
@@ -625,7 +565,7 @@ func scrapePage(UA string, ctx context.Context, urlToScrape string, cacheName st
 			select {
 			case <-ctx.Done():
 				timer.Stop()
-				log.Printf("❌ [%s] sleep interrupted", query)
+				log.Printf("❌ [%s] sleep interrupted", label)
 			case <-timer.C:
 				// Timer finished normally.
 			}
@@ -635,62 +575,34 @@ func scrapePage(UA string, ctx context.Context, urlToScrape string, cacheName st
 		}()
 	}
 
-	log.Printf("🔎 %s%s%s %s%s%s", ColorBold, query, ColorReset, ColorCyan, urlToScrape, ColorReset)
+	log.Printf("🔎 %s%s%s %s%s%s", ColorBold, label, ColorReset, ColorCyan, urlToScrape, ColorReset)
 
 	client := &http.Client{
 		Timeout: REQ_TIMEOUT,
 	}
 	req, err := http.NewRequestWithContext(ctx, "GET", urlToScrape, nil)
 	if err != nil {
-		log.Printf("[%s] 💥 error in request: %v", query, err)
-		return
+		log.Printf("[%s] 💥 error in request: %v", label, err)
+		return nil
 	}
 	req.Header.Set("User-Agent", UA)
 	res, err := client.Do(req)
 	if err != nil {
-		// log.Printf("[%s] 💥 error during request: %v", query, err)
-		return
+		// log.Printf("[%s] 💥 error during request: %v", label, err)
+		return nil
 	}
 	defer res.Body.Close()
 	if res.StatusCode != 200 {
-		log.Printf("[%s] 💥 request code [%d]: '%s'", query, res.StatusCode, res.Status)
-		return
+		log.Printf("[%s] 💥 request code [%d]: '%s'", label, res.StatusCode, res.Status)
+		return nil
 	}
 
 	bodyBytes, err := io.ReadAll(res.Body)
 	if err != nil {
-		log.Printf("[%s] 💥 error reading response body: %v", query, err)
-		return
-	}
-	resDoc, err := goquery.NewDocumentFromReader(bytes.NewReader(bodyBytes))
-	if err != nil {
-		log.Printf("[%s] 😵‍💫 error creating document: %v", query, err)
-		return
-	}
-
-	// extract goods from HTML
-	scrapedAt := time.Now().Format("20060102")
-	goodsList := extractGoodsFromHtml(resDoc, category, query, scrapedAt)
-
-	// save HTML to cache
-	saveHtmlToCache(cacheName, bodyBytes)
-
-	// extract goods images
-	mutex.Lock()
-	for _, good := range goodsList {
-		saveImageToCache(good.ImageUrl)
-	}
-	*allGoods = append(*allGoods, goodsList...)
-	total := len(*allGoods)
-	mutex.Unlock()
-
-	// console
-	if total == 0 {
-		log.Printf("🫥 %d %s %s0%s%s%s", total, query, ColorBlue, ColorCyan, urlToScrape, ColorReset)
-		return
-	} else {
-		log.Printf("📦 %d %s %s+%d%s", total, query, ColorBlue, len(goodsList), ColorReset)
+		log.Printf("[%s] 💥 error reading response body: %v", label, err)
+		return nil
 	}
+	return bodyBytes
 }
 
 // sanitizeString - remove spaces and newlines
@@ -712,11 +624,11 @@ func appendToCsv(goods []Goods, filename string, mutex *sync.Mutex) {
 
 	writer := csv.NewWriter(file)
 	writer.Comma = ';'
-	headers := []string{"Name", "Price", "PricePerUnit", "Discount", "Category", "SubCat", "Note", "Club", "Volume", "Market", "Validity", "Url", "ImageUrl", "Query", "ScrapedAt"}
+	headers := []string{"Name", "Price", "PriceHal", "Currency", "PricePerUnit", "PricePerUnitHal", "PricePerUnitUnit", "Doses", "PricePerDoseHal", "Discount", "DiscountPct", "OriginalPrice", "OriginalPriceHal", "OriginalDerived", "SuspiciousPrice", "PromoType", "PromoMinQty", "EffectivePriceHal", "Category", "SuggestedCat", "CatConfidence", "SubCat", "Note", "Club", "ClubProgram", "ClubMember", "ClubApp", "ClubCard", "PurchaseLimit", "Volume", "Market", "Validity", "ValidFrom", "ValidTo", "Upcoming", "Url", "ImageUrl", "LargeImageUrl", "Description", "Query", "ScrapedAt"}
 	writer.Write(headers)
 
 	for _, item := range goods {
-		item.ImageUrl = strings.TrimPrefix(item.ImageUrl, "https://img.kupi.cz/kupi/thumbs/")
+		item.ImageUrl = strings.TrimPrefix(item.ImageUrl, KOOPI_THUMBS_URL)
 		item.ImageUrl = strings.TrimPrefix(item.ImageUrl, "https://img.kupi.cz/img/no_img/no_discounts.png")
 		//item.ImageUrl = strings.TrimPrefix(item.ImageUrl, "https://img.kupi.cz/")
 
@@ -724,17 +636,43 @@ func appendToCsv(goods []Goods, filename string, mutex *sync.Mutex) {
 		writer.Write([]string{
 			item.Name,
 			item.Price,
+			strconv.Itoa(item.PriceHal),
+			item.Currency,
 			item.PricePerUnit,
+			strconv.Itoa(item.PricePerUnitHal),
+			item.PricePerUnitUnit,
+			strconv.Itoa(item.Doses),
+			strconv.Itoa(item.PricePerDoseHal),
 			item.Discount,
+			strconv.Itoa(item.DiscountPct),
+			item.OriginalPrice,
+			strconv.Itoa(item.OriginalPriceHal),
+			strconv.FormatBool(item.OriginalDerived),
+			strconv.FormatBool(item.SuspiciousPrice),
+			item.PromoType,
+			strconv.Itoa(item.PromoMinQty),
+			strconv.Itoa(item.EffectivePriceHal),
 			item.Category,
+			item.SuggestedCat,
+			strconv.FormatFloat(item.CatConfidence, 'f', 3, 64),
 			item.SubCat,
 			item.Note,
 			item.Club,
+			item.ClubProgram,
+			strconv.FormatBool(item.ClubMember),
+			strconv.FormatBool(item.ClubApp),
+			strconv.FormatBool(item.ClubCard),
+			strconv.Itoa(item.PurchaseLimit),
 			item.Volume,
 			item.Market,
 			item.Validity,
+			item.ValidFrom,
+			item.ValidTo,
+			strconv.FormatBool(item.Upcoming),
 			cleanUrl,
 			item.ImageUrl,
+			item.LargeImageUrl,
+			item.Description,
 			item.Query,
 			item.ScrapedAt,
 		})
@@ -765,10 +703,9 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 	defer file.Close()
 
 	var cleanedGoods []map[string]any
+	var exportedGoods []Goods
 	for _, item := range goods {
-		hashString := item.Name + item.Volume + item.Category + item.SubCat // unique good hash (for ID)
-		hash := md5.Sum([]byte(hashString))
-		md5Hash := hex.EncodeToString(hash[:])
+		md5Hash := productHash(item) // unique good hash (for ID)
 
 		// retrieve the offer count for the generic product
 		genericHashKey := item.Name + item.Volume + item.Category + item.SubCat
@@ -778,62 +715,64 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 		cleanedItem["id"] = md5Hash
 		cleanedItem["cat"] = item.Category
 		cleanedItem["subcat"] = item.SubCat
+		cleanedItem["catsrc"] = item.CatSource
 		cleanedItem["query"] = item.Query
 		cleanedItem["name"] = item.Name
 		cleanedItem["price"] = strings.Replace(item.Price, ",", ".", 1)
 		cleanedItem["ppunit"] = strings.Replace(item.PricePerUnit, ",", ".", 1)
 		cleanedItem["discount"] = item.Discount
+		cleanedItem["price_hal"] = item.PriceHal
+		cleanedItem["currency"] = item.Currency
+		cleanedItem["ppunit_hal"] = item.PricePerUnitHal
+		cleanedItem["ppunit_unit"] = item.PricePerUnitUnit
+		cleanedItem["discount_pct"] = item.DiscountPct
+		cleanedItem["original"] = item.OriginalPrice
+		cleanedItem["original_hal"] = item.OriginalPriceHal
+		cleanedItem["original_derived"] = item.OriginalDerived
+		cleanedItem["suspicious_price"] = item.SuspiciousPrice
+		cleanedItem["promo"] = item.PromoType
+		cleanedItem["promo_qty"] = item.PromoMinQty
+		cleanedItem["effective_hal"] = item.EffectivePriceHal
+		cleanedItem["low30_hal"] = item.Low30Hal
+		cleanedItem["low90_hal"] = item.Low90Hal
+		cleanedItem["fake_discount"] = item.FakeDiscount
+		cleanedItem["pinned"] = item.Pinned
+		cleanedItem["doses"] = item.Doses
+		cleanedItem["ppdose_hal"] = item.PricePerDoseHal
+		cleanedItem["ppdose"] = ""
+		if item.Doses > 0 {
+			cleanedItem["ppdose"] = formatHal(item.PricePerDoseHal, item.Currency) + "\u00a0/\u00a0dávka"
+		}
 		cleanedItem["note"] = item.Note
 		cleanedItem["club"] = item.Club
+		cleanedItem["club_program"] = item.ClubProgram
+		cleanedItem["club_member"] = item.ClubMember
+		cleanedItem["club_app"] = item.ClubApp
+		cleanedItem["club_card"] = item.ClubCard
+		cleanedItem["limit"] = item.PurchaseLimit
 		cleanedItem["volume"] = item.Volume
+		cleanedItem["vol_qty"] = item.VolQty
+		cleanedItem["vol_max"] = item.VolMax
+		cleanedItem["vol_unit"] = item.VolUnit
+		cleanedItem["vol_packs"] = item.VolPacks
+		cleanedItem["vol_piece"] = item.VolPiece
 		cleanedItem["market"] = item.Market
+		cleanedItem["chain"] = marketRegistry.chain(item.Market)
 		cleanedItem["validity"] = item.Validity
+		cleanedItem["valid_from"] = item.ValidFrom
+		cleanedItem["valid_to"] = item.ValidTo
+		cleanedItem["upcoming"] = item.Upcoming
+		cleanedItem["description"] = item.Description
+		cleanedItem["image_large"] = item.LargeImageUrl
 		cleanedItem["url"] = strings.TrimPrefix(item.Url, KOOPI_HOME_URL)
 		cleanedItem["scrapedat"] = item.ScrapedAt
 
 		// validity logic
-		// cat data.json | jq '.goods[].validity' | sort | uniq
-		scraped := item.ScrapedAt
-		validity := item.Validity
-		todayStr := time.Now().Format("20060102")
-		yesterdayStr := time.Now().AddDate(0, 0, -1).Format("20060102")
-
-		// text transformations
-		if scraped == yesterdayStr {
-			if strings.Contains(validity, "zítra končí") {
-				validity = "dnes končí"
-			} else if strings.Contains(validity, "dnes končí") {
-				continue
-			}
-		} else if scraped != todayStr {
-			if strings.Contains(validity, "dnes končí") || strings.Contains(validity, "zítra končí") {
-				continue
-			}
-		}
-
-		// color matching
-		valcol := "green"
-		if strings.Contains(validity, "dnes končí") {
-			valcol = "red"
-		}
-		if strings.Contains(validity, "zítra končí") {
-			valcol = "orange"
-		}
-
-		// validity date in the future?
-		match := reFutureDate.FindStringSubmatch(validity)
-		if len(match) >= 3 {
-			d, _ := strconv.Atoi(match[1])
-			m, _ := strconv.Atoi(match[2])
-			now := time.Now()
-			startDate := time.Date(now.Year(), time.Month(m), d, 0, 0, 0, 0, time.Local)
-			if startDate.Before(now.AddDate(0, 0, -1)) {
-				startDate = startDate.AddDate(1, 0, 0)
-			}
-			if startDate.Sub(now).Hours() > 120 {
-				valcol = "blue"
-			}
+		validity, valcol, expired := validityState(item, time.Now())
+		if expired {
+			continue
 		}
+		exportedGoods = append(exportedGoods, item)
 
 		// save the values
 		cleanedItem["valcol"] = valcol
@@ -845,7 +784,7 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 		} else if before0, ok0 := strings.CutSuffix(imageURL, ".jpg"); ok0 {
 			imageURL = before0 + ".webp"
 		}
-		imageURL = strings.TrimPrefix(imageURL, "https://img.kupi.cz/kupi/thumbs/")
+		imageURL = strings.TrimPrefix(imageURL, KOOPI_THUMBS_URL)
 		imageURL = strings.TrimPrefix(imageURL, "https://img.kupi.cz/img/no_img/no_discounts.png")
 		if imageURL == "" || strings.Contains(imageURL, "no_discounts") {
 			imageURL = "default.webp"
@@ -861,22 +800,35 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 		cleanedGoods = append(cleanedGoods, cleanedItem)
 	}
 
-	// convert id hashes to integers, find unique keywords, create hashmap
-	id := 1
+	// convert id hashes to permanent integers, find unique keywords, create hashmap
+	registry := loadIdRegistry(config.Paths.Ids)
 	hashmap := make(map[string]int)
 	wordsSeen := make(map[string]bool)
 	keywordsIndex := make(map[string][]int)
 	cleaner := strings.NewReplacer("%", "", "°", "", ",", "", "!", "")
 	var uniqueWords []string
+	var fakeIDs []int
+	var pinnedIDs []int
 	for i := range cleanedGoods {
 		hash := cleanedGoods[i]["id"].(string)
-		if _, exists := hashmap[hash]; !exists {
-			hashmap[hash] = id
-			id++
-		}
+		hashmap[hash] = registry.id(hash)
 		currentIntID := hashmap[hash]
 		cleanedGoods[i]["id"] = currentIntID
 
+		// fake discounts filter
+		if cleanedGoods[i]["fake_discount"].(bool) {
+			if len(fakeIDs) == 0 || fakeIDs[len(fakeIDs)-1] != currentIntID {
+				fakeIDs = append(fakeIDs, currentIntID)
+			}
+		}
+
+		// pinned items
+		if cleanedGoods[i]["pinned"].(bool) {
+			if len(pinnedIDs) == 0 || pinnedIDs[len(pinnedIDs)-1] != currentIntID {
+				pinnedIDs = append(pinnedIDs, currentIntID)
+			}
+		}
+
 		// processing unique keywords
 		name := strings.ToLower(cleanedGoods[i]["name"].(string))
 		for w := range strings.FieldsSeq(name) {
@@ -897,6 +849,7 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 		}
 	}
 	sort.Strings(uniqueWords)
+	registry.save(config.Paths.Ids)
 
 	// reverse hashmap for quick JavaScript pairing
 	reversedHashmap := make(map[int]string)
@@ -916,10 +869,14 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 	outputData["count"] = len(cleanedGoods)
 	outputData["goods"] = cleanedGoods
 	outputData["markets"] = markets
+	outputData["chains"] = marketRegistry.export(exportedGoods)
 	outputData["keywords"] = strings.Join(uniqueWords, " ")
 	outputData["keywordsindex"] = keywordsIndex
 	outputData["idhashmap"] = reversedHashmap
 	outputData["catcounts"] = catCounts
+	outputData["categories"] = taxonomy.export(catCounts)
+	outputData["fakeids"] = fakeIDs
+	outputData["pinnedids"] = pinnedIDs
 
 	// save to JSON
 	encoder := json.NewEncoder(file)
@@ -932,20 +889,69 @@ func appendToJson(goods []Goods, filename string, markets []string, mutex *sync.
 
 // main
 func main() {
+	log.SetFlags(0)
+
+	// read-only commands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			if !configCommand(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		case "rules":
+			if err := loadConfig(CONFIG_JSON); err != nil {
+				log.Fatalf("[%s] 💥 error loading configuration: %v", CONFIG_JSON, err)
+			}
+			rulesCommand()
+			return
+		case "classify":
+			if err := loadConfig(CONFIG_JSON); err != nil {
+				log.Fatalf("[%s] 💥 error loading configuration: %v", CONFIG_JSON, err)
+			}
+			classifyCommand(os.Args[2:])
+			return
+		case "lint":
+			if err := loadConfig(CONFIG_JSON); err != nil {
+				log.Fatalf("[%s] 💥 error loading configuration: %v", CONFIG_JSON, err)
+			}
+			if !lintCommand(os.Args[2:]) {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	if !checkLock() {
 		os.Exit(1)
 	}
 	defer unlockLock()
 
-	log.SetFlags(0)
+	// load configuration
+	if err := loadConfig(CONFIG_JSON); err != nil {
+		log.Fatalf("[%s] 💥 error loading configuration: %v", CONFIG_JSON, err)
+	}
 
-	// just to be sure
-	for i, v := range blockedGoods {
-		blockedGoods[i] = strings.ToLower(v)
+	// commands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rehydrate":
+			rehydrate(os.Args[2:])
+		case "history":
+			historyCommand(os.Args[2:])
+		case "hashmap":
+			hashmapCommand(os.Args[2:])
+		case "crawl":
+			crawlCommand()
+		default:
+			fmt.Printf("❓ Unknown command: %s\n", os.Args[1])
+			fmt.Println("Usage: koopi [rehydrate [file.json] | history [-full] | hashmap [-full] | config [file.json] | rules | lint [scrape.csv] | classify [file.json] | crawl]")
+		}
+		return
 	}
 
 	// set random UA
-	UA := UserAgents[rand.Intn(len(UserAgents))]
+	UA := config.UserAgents[rand.Intn(len(config.UserAgents))]
 	log.Printf("UA: %s", UA)
 
 	// set rate limiter
@@ -955,85 +961,19 @@ func main() {
 	}
 
 	// load input CSV
-	file, err := os.Open(INPUT_CSV)
+	queries, problems, err := readScrapeCsv(config.Paths.InputCsv)
 	if err != nil {
-		log.Fatalf("[%s] 💥 error opening: %v", INPUT_CSV, err)
+		log.Fatalf("[%s] 💥 error reading: %v", config.Paths.InputCsv, err)
 	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.Comma = ','
-	reader.FieldsPerRecord = -1
-
-	inputRecords, err := reader.ReadAll()
-	if err != nil {
-		log.Fatalf("[%s] 💥 error reading: %v", INPUT_CSV, err)
+	for _, p := range problems {
+		log.Printf("⚠️ [%s] %s", config.Paths.InputCsv, p)
 	}
 
-	if len(inputRecords) == 0 {
-		log.Printf("😐️ [%s] is empty. Nothing to scrape.", INPUT_CSV)
+	if len(queries) == 0 {
+		log.Printf("😐️ [%s] is empty. Nothing to scrape.", config.Paths.InputCsv)
 		return
 	}
 
-	var urlsToScrape []struct {
-		url      string
-		cacheKey string
-		category string
-		query    string
-	}
-
-	// generate URLs to scrape
-	for _, record := range inputRecords {
-		if len(record) < 2 || strings.TrimSpace(record[0]) == "" || strings.TrimSpace(record[1]) == "" {
-			continue
-		}
-		category := strings.TrimSpace(record[0])
-		query := strings.TrimSpace(record[1])
-		pages, _ := strconv.Atoi(strings.TrimSpace(record[2]))
-		escapedQuery := url.QueryEscape(query)
-
-		for pageNum := 1; pageNum <= pages; pageNum++ {
-			var urlStr string
-			if pageNum == 1 {
-				urlStr = KOOPI_SEARCH_URL + escapedQuery
-			} else {
-				urlStr = fmt.Sprintf("%s%s%s%d", KOOPI_SEARCH_URL, escapedQuery, KOOPI_SUBPAGE, pageNum)
-			}
-			cacheKey := fmt.Sprintf("%s-%d.html", strings.ReplaceAll(query, " ", "-"), pageNum)
-			urlsToScrape = append(urlsToScrape, struct {
-				url      string
-				cacheKey string
-				category string
-				query    string
-			}{urlStr, cacheKey, category, query})
-		}
-	}
-
-	urlsToScrape2 := make([]struct {
-		url      string
-		cacheKey string
-		category string
-		query    string
-	}, len(urlsToScrape))
-
-	// unshuffled original copy of the list
-	copy(urlsToScrape2, urlsToScrape)
-
-	// shuffle URLs
-	rand.Shuffle(len(urlsToScrape), func(i, j int) {
-		urlsToScrape[i], urlsToScrape[j] = urlsToScrape[j], urlsToScrape[i]
-	})
-
-	// limits
-	if len(urlsToScrape) == 0 {
-		log.Println("🍀 Nothing to scrape.")
-		return
-	}
-
-	if len(urlsToScrape) > MAX_SCRAPED_GOODS {
-		urlsToScrape = urlsToScrape[:MAX_SCRAPED_GOODS]
-	}
-
 	var newScrapedGoods []Goods
 	var csvMutex sync.Mutex
 	var goodsMutex sync.Mutex
@@ -1053,25 +993,63 @@ func main() {
 	// concurrency
 	concurrencyLimit := make(chan struct{}, MAX_THREADS)
 
-	// workers
-	for _, urlData := range urlsToScrape {
-		wg.Add(1)
-		concurrencyLimit <- struct{}{}
-		go func(urlData struct {
-			url      string
-			cacheKey string
-			category string
-			query    string
-		}) {
-			defer func() {
-				<-concurrencyLimit
-			}()
-			scrapePage(UA, ctx, urlData.url, urlData.cacheKey, urlData.category, urlData.query, &newScrapedGoods, &goodsMutex, &wg)
-		}(urlData)
+	// first pages, then the pages discovered by pagination links up to PAGES
+	pages := make(map[string]*QueryPages)
+	budget := MAX_SCRAPED_GOODS
+	for round := 1; ctx.Err() == nil; round++ {
+		// generate URLs to scrape
+		urlsToScrape := nextPageTasks(queries, pages)
+
+		// shuffle URLs
+		rand.Shuffle(len(urlsToScrape), func(i, j int) {
+			urlsToScrape[i], urlsToScrape[j] = urlsToScrape[j], urlsToScrape[i]
+		})
+
+		// limits
+		if len(urlsToScrape) == 0 {
+			if round == 1 {
+				log.Println("🍀 Nothing to scrape.")
+				return
+			}
+			break
+		}
+		if len(urlsToScrape) > budget {
+			urlsToScrape = urlsToScrape[:budget]
+		}
+		budget -= len(urlsToScrape)
+
+		// workers
+		for _, urlData := range urlsToScrape {
+			wg.Add(1)
+			concurrencyLimit <- struct{}{}
+			go func(urlData ScrapeTask) {
+				defer func() {
+					<-concurrencyLimit
+					wg.Done()
+				}()
+				last := scrapePage(UA, ctx, urlData.url, urlData.cacheKey, urlData.category, urlData.query, urlData.page, urlData.filter, &newScrapedGoods, &goodsMutex)
+				goodsMutex.Lock()
+				pages[urlData.query].Found = max(pages[urlData.query].Found, last)
+				goodsMutex.Unlock()
+			}(urlData)
+		}
+
+		// wait for workers to finish
+		wg.Wait()
+		if budget == 0 {
+			break
+		}
 	}
 
-	// wait for workers to finish
-	wg.Wait()
+	// optional category sections, the offers missed by the queries join the scrape
+	if config.CrawlMerge && len(config.CrawlSections) > 0 && ctx.Err() == nil {
+		newScrapedGoods = append(newScrapedGoods, crawlSections(UA, ctx, config.CrawlSections)...)
+	}
+
+	// optional detail pages
+	if config.ScrapeDetails {
+		newScrapedGoods = scrapeDetails(UA, ctx, newScrapedGoods, queries)
+	}
 
 	// deduplication
 	finalGoods := deduplicateGoods(newScrapedGoods)
@@ -1082,18 +1060,31 @@ func main() {
 	uniqueVolumes := make(map[string]struct{})
 
 	// process deterministic category
+	matched := assignCategories(finalGoods, queries, config.Paths.CategoryTrace)
+
+	// suggested category (when the deterministic match is missing)
+	classifyGoods(finalGoods, matched, queries, config.Paths.ReviewReport)
+
+	// manual overrides
+	finalGoods = applyOverrides(finalGoods, config.Paths.Overrides)
+
+	// category scoped blocking rules
+	finalGoods = blockScopedGoods(finalGoods)
+
+	// markets without registry entry or logo
+	reportMarkets(finalGoods, config.Paths.MarketLogos)
+
+	// original prices that do not match the discount
+	reportPrices(finalGoods)
+
+	// price per dose (depends on the final category)
 	for i := range finalGoods {
-		for _, mapping := range urlsToScrape2 {
-			if mapping.query == "" {
-				continue
-			}
-			if strings.Contains(strings.ToLower(finalGoods[i].Name), strings.ToLower(mapping.query)) {
-				finalGoods[i].Category = mapping.category
-				break
-			}
-		}
+		parseDoses(&finalGoods[i])
 	}
 
+	// lowest prices from history
+	applyPriceHistory(finalGoods)
+
 	// unique markets and volumes
 	for _, good := range finalGoods {
 		if good.Market != "" {
@@ -1128,13 +1119,17 @@ func main() {
 	sort.Slice(finalGoods, func(i, j int) bool {
 		return c.CompareString(finalGoods[i].Name, finalGoods[j].Name) < 0
 	})
-	appendToCsv(finalGoods, OUTPUT_CSV, &csvMutex)
+	appendToCsv(finalGoods, config.Paths.OutputCsv, &csvMutex)
 
 	cExport := collate.New(language.Czech, collate.IgnoreCase)
 	sort.Slice(marketsList, func(i, j int) bool {
 		return cExport.CompareString(marketsList[i], marketsList[j]) < 0
 	})
-	appendToJson(finalGoods, OUTPUT_JSON, marketsList, &csvMutex)
+	appendToJson(finalGoods, config.Paths.OutputJson, marketsList, &csvMutex)
+	exportHistory(finalGoods, config.Paths.OutputHistory)
+	saveBlockReport(config.Paths.BlockReport)
+
+	reportPages(queries, pages)
 
 	fmt.Printf("\n🍀 Scraper finished with %d unique items.\n\n", len(finalGoods))
 
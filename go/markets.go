@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// RegExps
+var (
+	// barva #rrggbb
+	reColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// loyalty program of the chain
+type LoyaltyProgram struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// market chain, raw kupi names are the chain id and the aliases
+//
+//	{"chain": "Albert", "name": "Albert", "logo": "Albert.webp", "aliases": ["Albert supermarket", "Albert hypermarket"]}
+type Market struct {
+	Chain   string          `json:"chain"`
+	Name    string          `json:"name"`
+	Logo    string          `json:"logo,omitempty"`
+	Color   string          `json:"color,omitempty"`
+	Loyalty *LoyaltyProgram `json:"loyalty,omitempty"`
+	Aliases []string        `json:"aliases,omitempty"`
+}
+
+// chain in koopi.json
+type ChainExport struct {
+	Id      string          `json:"id"`
+	Name    string          `json:"name"`
+	Logo    string          `json:"logo"`
+	Color   string          `json:"color"`
+	Loyalty *LoyaltyProgram `json:"loyalty"`
+	Markets []string        `json:"markets"`
+	Count   int             `json:"count"`
+}
+
+// market registry with raw name lookup
+type MarketRegistry struct {
+	list  []Market
+	index map[string]int // lower case raw name → list index
+}
+
+// current market registry
+var marketRegistry MarketRegistry
+
+// readMarkets - read the market registry file
+func readMarkets(filename string) (MarketRegistry, error) {
+	var r MarketRegistry
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(content, &r.list); err != nil {
+		return r, err
+	}
+	r.index = make(map[string]int)
+	for i, m := range r.list {
+		for _, name := range append([]string{m.Chain}, m.Aliases...) {
+			if _, ok := r.index[strings.ToLower(name)]; !ok {
+				r.index[strings.ToLower(name)] = i
+			}
+		}
+	}
+	return r, nil
+}
+
+// validate - list malformed market entries
+func (r MarketRegistry) validate() []string {
+	var problems []string
+	seen := make(map[string]string)
+	programs := make(map[string]string)
+	for i, m := range r.list {
+		name := fmt.Sprintf("markets[%d]", i)
+		if strings.TrimSpace(m.Chain) == "" {
+			problems = append(problems, fmt.Sprintf("%s: empty chain", name))
+			continue
+		}
+		if m.Name == "" {
+			problems = append(problems, fmt.Sprintf("%s: %s has no name", name, m.Chain))
+		}
+		if m.Color != "" && !reColor.MatchString(m.Color) {
+			problems = append(problems, fmt.Sprintf("%s: %s has bad color %q", name, m.Chain, m.Color))
+		}
+		for _, raw := range append([]string{m.Chain}, m.Aliases...) {
+			if other, ok := seen[strings.ToLower(raw)]; ok {
+				problems = append(problems, fmt.Sprintf("%s: %q is already used by %s", name, raw, other))
+			} else {
+				seen[strings.ToLower(raw)] = m.Chain
+			}
+		}
+		if p := m.Loyalty; p != nil {
+			switch {
+			case p.Id == "" || p.Name == "":
+				problems = append(problems, fmt.Sprintf("%s: %s loyalty program needs id and name", name, m.Chain))
+			case programs[p.Id] != "":
+				problems = append(problems, fmt.Sprintf("%s: loyalty program %s is already used by %s", name, p.Id, programs[p.Id]))
+			default:
+				programs[p.Id] = m.Chain
+			}
+		}
+	}
+	return problems
+}
+
+// loadMarkets - load the market registry file into the current registry
+func loadMarkets(filename string) error {
+	r, err := readMarkets(filename)
+	if err != nil {
+		return err
+	}
+	marketRegistry = r
+	return nil
+}
+
+// lookup - registry entry of the raw market name, nil if unknown
+func (r MarketRegistry) lookup(raw string) *Market {
+	if i, ok := r.index[strings.ToLower(raw)]; ok {
+		return &r.list[i]
+	}
+	return nil
+}
+
+// chain - chain id of the raw market name, unknown names are their own chain
+func (r MarketRegistry) chain(raw string) string {
+	if m := r.lookup(raw); m != nil {
+		return m.Chain
+	}
+	return raw
+}
+
+// export - chains of the goods in Czech order
+func (r MarketRegistry) export(goods []Goods) []ChainExport {
+	chains := make(map[string]*ChainExport)
+	for _, g := range goods {
+		if g.Market == "" {
+			continue
+		}
+		id := r.chain(g.Market)
+		c, ok := chains[id]
+		if !ok {
+			c = &ChainExport{Id: id, Name: id, Markets: []string{}}
+			if m := r.lookup(g.Market); m != nil {
+				c.Name, c.Logo, c.Color, c.Loyalty = m.Name, m.Logo, m.Color, m.Loyalty
+			}
+			chains[id] = c
+		}
+		if !containsFold(c.Markets, g.Market) {
+			c.Markets = append(c.Markets, g.Market)
+		}
+		c.Count++
+	}
+
+	var list []ChainExport
+	for _, c := range chains {
+		sort.Strings(c.Markets)
+		list = append(list, *c)
+	}
+	cmp := collate.New(language.Czech, collate.IgnoreCase)
+	sort.Slice(list, func(i, j int) bool {
+		return cmp.CompareString(list[i].Name, list[j].Name) < 0
+	})
+	return list
+}
+
+// reportMarkets - log markets without a registry entry or a logo
+func reportMarkets(goods []Goods, logoDir string) {
+	reported := make(map[string]bool)
+	for _, g := range goods {
+		if g.Market == "" || reported[g.Market] {
+			continue
+		}
+		reported[g.Market] = true
+		m := marketRegistry.lookup(g.Market)
+		switch {
+		case m == nil:
+			log.Printf("❓ market %s%s%s is not in the registry", ColorBold, g.Market, ColorReset)
+		case m.Logo == "":
+			log.Printf("🖼️ market %s%s%s has no logo", ColorBold, g.Market, ColorReset)
+		default:
+			if _, err := os.Stat(filepath.Join(logoDir, m.Logo)); err != nil {
+				log.Printf("🖼️ market %s%s%s logo %s is missing in %s", ColorBold, g.Market, ColorReset, m.Logo, logoDir)
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	OMNIBUS_DAYS      = 30
+	OMNIBUS_LONG_DAYS = 90
+
+	OMNIBUS_RUN_GAP_DAYS = 7 // longest gap between stems inside one promotion run
+)
+
+// lowestPrice - lowest price of the market in history rows between from (inclusive) and to (exclusive)
+func lowestPrice(rows []HistoryRow, market string, from string, to string) int {
+	lowest := 0
+	for _, row := range rows {
+		if row.Market != market || row.Date < from || row.Date >= to {
+			continue
+		}
+		if lowest == 0 || row.PriceHal < lowest {
+			lowest = row.PriceHal
+		}
+	}
+	return lowest
+}
+
+// promotionStart - first day of the current promotion run, the history rows of the market right before
+// the end with the same price and discount, false if the history starts inside the run
+func promotionStart(rows []HistoryRow, g Goods, end time.Time) (time.Time, bool) {
+	start := end
+	for i := len(rows) - 1; i >= 0; i-- {
+		row := rows[i]
+		day := parseIsoDate(row.Date)
+		if row.Market != g.Market || day.IsZero() || !day.Before(start) {
+			continue
+		}
+		if start.Sub(day) > OMNIBUS_RUN_GAP_DAYS*24*time.Hour || row.PriceHal != g.PriceHal || row.DiscountPct != g.DiscountPct {
+			return start, true
+		}
+		start = day
+	}
+	return start, false
+}
+
+// applyPriceHistory - fill the lowest prices in the last 30 and 90 days and flag fake discounts
+func applyPriceHistory(goods []Goods) {
+	db, err := openHistory(true)
+	if err != nil {
+		log.Printf("🫥 [%s] no price history: %v", config.Paths.HistoryDb, err)
+		return
+	}
+	defer db.Close()
+
+	fake := 0
+	db.View(func(tx *bolt.Tx) error {
+		cache := make(map[string][]HistoryRow)
+		for i := range goods {
+			g := &goods[i]
+			g.Low30Hal, g.Low90Hal, g.FakeDiscount = 0, 0, false
+
+			scraped, err := time.ParseInLocation(DATE_SCRAPED, g.ScrapedAt, time.Local)
+			if err != nil || g.PriceHal == 0 || g.Upcoming {
+				continue
+			}
+			hash := productHash(*g)
+			rows, ok := cache[hash]
+			if !ok {
+				rows = loadProductHistory(tx, hash)
+				cache[hash] = rows
+			}
+
+			// only days before the promotion started count, "platí do" validity leaves the start at the scrape day,
+			// then the earlier days of the same promotion are found in the history
+			end, known := dayStart(scraped), true
+			if from := parseIsoDate(g.ValidFrom); !from.IsZero() && from.Before(end) {
+				end = from
+			} else {
+				end, known = promotionStart(rows, *g, end)
+			}
+			to := end.Format(DATE_ISO)
+			g.Low30Hal = lowestPrice(rows, g.Market, end.AddDate(0, 0, -OMNIBUS_DAYS).Format(DATE_ISO), to)
+			g.Low90Hal = lowestPrice(rows, g.Market, end.AddDate(0, 0, -OMNIBUS_LONG_DAYS).Format(DATE_ISO), to)
+
+			// "discount" that is not lower than the recent prices, only when the promotion start is known
+			if known && g.DiscountPct > 0 && g.Low30Hal > 0 && g.PriceHal >= g.Low30Hal {
+				g.FakeDiscount = true
+				fake++
+			}
+		}
+		return nil
+	})
+
+	log.Printf("🤥 fake discounts: %s%d%s", ColorRed, fake, ColorReset)
+}
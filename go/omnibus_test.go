@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestPromotionStart(t *testing.T) {
+	g := Goods{Market: "Lidl", PriceHal: 4990, DiscountPct: 30}
+	end := parseIsoDate("2026-07-24")
+	row := func(date string, price, pct int) HistoryRow {
+		return HistoryRow{Date: date, Market: "Lidl", PriceHal: price, DiscountPct: pct}
+	}
+	tests := []struct {
+		name  string
+		rows  []HistoryRow
+		start string
+		known bool
+	}{
+		{"run after a regular price", []HistoryRow{row("2026-07-18", 6990, 0), row("2026-07-20", 4990, 30), row("2026-07-23", 4990, 30)}, "2026-07-20", true},
+		{"history starts inside the run", []HistoryRow{row("2026-07-20", 4990, 30), row("2026-07-23", 4990, 30)}, "2026-07-20", false},
+		{"gap ends the run", []HistoryRow{row("2026-07-01", 4990, 30), row("2026-07-23", 4990, 30)}, "2026-07-23", true},
+		{"other market", []HistoryRow{{Date: "2026-07-23", Market: "Penny", PriceHal: 4990, DiscountPct: 30}, row("2026-07-22", 6990, 0)}, "2026-07-24", true},
+	}
+	for _, tt := range tests {
+		start, known := promotionStart(tt.rows, g, end)
+		if got := start.Format(DATE_ISO); got != tt.start || known != tt.known {
+			t.Errorf("%s: promotionStart = %s, %v, want %s, %v", tt.name, got, known, tt.start, tt.known)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// manual fix of one product, keyed by the product hash or the kupi URL
+//
+//	{"hash": "0cc175b9c0f1b6a831c399e269772661", "action": "hide", "note": "not a drugstore item"}
+//	{"url": "/sleva/jar", "action": "rename", "value": "Jar na nádobí"}
+//	{"hash": "...", "action": "recategorize", "value": "NÁDOBÍ"}
+//	{"url": "/sleva/jar", "action": "set-image", "value": "jar.png"}
+//	{"hash": "...", "action": "pin"}
+type Override struct {
+	Hash   string `json:"hash,omitempty"`
+	Url    string `json:"url,omitempty"`
+	Action string `json:"action"`
+	Value  string `json:"value,omitempty"`
+	Note   string `json:"note,omitempty"`
+}
+
+// actions, true if the value is required
+var overrideActions = map[string]bool{
+	"hide":         false,
+	"rename":       true,
+	"recategorize": true,
+	"set-image":    true,
+	"pin":          false,
+}
+
+// String - short override description for logs
+func (o Override) String() string {
+	key := o.Hash
+	if key == "" {
+		key = o.Url
+	}
+	if o.Value != "" {
+		return fmt.Sprintf("%s %s → %q", o.Action, key, o.Value)
+	}
+	return fmt.Sprintf("%s %s", o.Action, key)
+}
+
+// matches - check the override key against the product hash and URL
+func (o Override) matches(hash string, url string) bool {
+	if o.Hash != "" {
+		return o.Hash == hash
+	}
+	return strings.TrimPrefix(o.Url, KOOPI_HOME_URL) == strings.TrimPrefix(url, KOOPI_HOME_URL)
+}
+
+// readOverrides - read the overrides file, missing file means no overrides
+func readOverrides(filename string) ([]Override, error) {
+	content, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var overrides []Override
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// validateOverrides - list malformed overrides
+func validateOverrides(overrides []Override, t Taxonomy) []string {
+	var problems []string
+	seen := make(map[string]int)
+	for i, o := range overrides {
+		name := fmt.Sprintf("overrides[%d]", i)
+		needsValue, ok := overrideActions[o.Action]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: unknown action %q", name, o.Action))
+			continue
+		case (o.Hash == "") == (o.Url == ""):
+			problems = append(problems, fmt.Sprintf("%s: use exactly one of hash or url", name))
+			continue
+		case needsValue && strings.TrimSpace(o.Value) == "":
+			problems = append(problems, fmt.Sprintf("%s: %s needs a value", name, o.Action))
+			continue
+		case !needsValue && o.Value != "":
+			problems = append(problems, fmt.Sprintf("%s: %s takes no value", name, o.Action))
+		}
+		if o.Action == "recategorize" && !t.known(o.Value) {
+			problems = append(problems, fmt.Sprintf("%s: unknown category %s", name, o.Value))
+		}
+		if o.Action == "set-image" && strings.Contains(o.Value, "://") && !strings.HasPrefix(o.Value, KOOPI_THUMBS_URL) {
+			problems = append(problems, fmt.Sprintf("%s: image has to be a name or %s URL", name, KOOPI_THUMBS_URL))
+		}
+		key := o.Hash + strings.TrimPrefix(o.Url, KOOPI_HOME_URL) + " " + o.Action
+		if j, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s duplicates entry %d", name, o, j))
+		} else {
+			seen[key] = i
+		}
+	}
+	return problems
+}
+
+// applyOverrides - apply manual overrides, log matched and stale ones
+func applyOverrides(goods []Goods, filename string) []Goods {
+	overrides, err := readOverrides(filename)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error reading: %v", filename, err)
+	}
+	if len(overrides) == 0 {
+		return goods
+	}
+
+	hits := make([]int, len(overrides))
+	var kept []Goods
+	for _, g := range goods {
+		// keys are matched before any change
+		hash := productHash(g)
+		url := g.Url
+		hidden := false
+		for i, o := range overrides {
+			if !o.matches(hash, url) {
+				continue
+			}
+			hits[i]++
+			switch o.Action {
+			case "hide":
+				hidden = true
+			case "rename":
+				g.Name = o.Value
+			case "recategorize":
+				g.Category = o.Value
+				g.CatSource = CAT_SOURCE_OVERRIDE
+			case "set-image":
+				g.ImageUrl = o.Value
+				if !strings.Contains(o.Value, "://") {
+					g.ImageUrl = KOOPI_THUMBS_URL + o.Value
+				}
+				saveImageToCache(g.ImageUrl)
+			case "pin":
+				g.Pinned = true
+			}
+			// IDs and price history stay with the original product
+			g.Hash = hash
+		}
+		if !hidden {
+			kept = append(kept, g)
+		}
+	}
+
+	stale := 0
+	for i, o := range overrides {
+		if hits[i] == 0 {
+			stale++
+			log.Printf("💤 stale override: %s", o)
+			continue
+		}
+		log.Printf("✏️ override: %s (%d items)", o, hits[i])
+	}
+	log.Printf("✏️ overrides: %d matched, %d stale, %d items hidden", len(overrides)-stale, stale, len(goods)-len(kept))
+	return kept
+}
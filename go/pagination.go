@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// RegExps
+var (
+	// číslo stránky v odkazu
+	rePageParam = regexp.MustCompile(`[?&]page=(\d+)`)
+)
+
+// pagination state of one query, PAGES in scrape.csv is the upper bound
+type QueryPages struct {
+	Scheduled int // pages scheduled so far
+	Found     int // last result page seen, 0 if unknown
+}
+
+// discoverPages - last result page linked from the current page
+func discoverPages(doc *goquery.Document, current int) int {
+	last := current
+	doc.Find("a[href]").Each(func(i int, a *goquery.Selection) {
+		href, _ := a.Attr("href")
+		if match := rePageParam.FindStringSubmatch(href); len(match) == 2 {
+			if n, err := strconv.Atoi(match[1]); err == nil && n > last {
+				last = n
+			}
+		}
+	})
+
+	// "next page" link without the page number
+	if doc.Find("a[rel='next'], link[rel='next'], .pagination .next a").Length() > 0 && last == current {
+		last = current + 1
+	}
+	return last
+}
+
+// nextPageTasks - first pages of new queries and the discovered pages not scheduled yet
+func nextPageTasks(queries []ScrapeQuery, pages map[string]*QueryPages) []ScrapeTask {
+	var tasks []ScrapeTask
+	for _, q := range queries {
+		if q.Pages == 0 {
+			continue
+		}
+		p, ok := pages[q.Query]
+		if !ok {
+			p = &QueryPages{}
+			pages[q.Query] = p
+		}
+		last := min(p.Found, q.Pages)
+		if p.Scheduled == 0 {
+			last = 1
+		}
+		for pageNum := p.Scheduled + 1; pageNum <= last; pageNum++ {
+			tasks = append(tasks, q.task(pageNum))
+		}
+		p.Scheduled = max(p.Scheduled, last)
+	}
+	return tasks
+}
+
+// reportPages - suggest PAGES values matching the discovered result pages
+func reportPages(queries []ScrapeQuery, pages map[string]*QueryPages) {
+	suggested := 0
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		// duplicate rows share the pages of the first one
+		if seen[q.Query] {
+			continue
+		}
+		seen[q.Query] = true
+		p, ok := pages[q.Query]
+		if !ok || p.Found == 0 || p.Found == q.Pages {
+			continue
+		}
+		if suggested == 0 {
+			fmt.Println()
+		}
+		suggested++
+		more := ""
+		if p.Found > q.Pages {
+			more = fmt.Sprintf(" %s(at least %d pages exist)%s", ColorDim, p.Found, ColorReset)
+		}
+		fmt.Printf("📑 [%s:%d] %s%s%s PAGES %d → %s%d%s%s\n", config.Paths.InputCsv, q.Line, ColorBold, q.Query, ColorReset, q.Pages, ColorBlue, p.Found, ColorReset, more)
+	}
+	if suggested > 0 {
+		fmt.Printf("📑 %d PAGES suggestions\n", suggested)
+	}
+}
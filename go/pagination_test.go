@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDiscoverPages(t *testing.T) {
+	tests := []struct {
+		html    string
+		current int
+		want    int
+	}{
+		{`<a href="/hledej?f=jar&page=2">2</a> <a href="/hledej?f=jar&page=5">5</a>`, 1, 5},
+		{`<a href="/hledej?f=jar&page=3">3</a>`, 4, 4}, // links to earlier pages only
+		{`<link rel="next" href="/hledej?f=jar">`, 2, 3},
+		{`<a href="/hledej?f=jar">jar</a>`, 1, 1},
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := discoverPages(doc, tt.current); got != tt.want {
+			t.Errorf("discoverPages(%s, %d) = %d, want %d", tt.html, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestNextPageTasks(t *testing.T) {
+	queries := []ScrapeQuery{
+		{Line: 1, Query: "ariel", Pages: 3},
+		{Line: 2, Query: "elmex", Pages: 2},
+		{Line: 3, Query: "persil", Pages: 0},
+		{Line: 4, Query: "ariel", Pages: 3},
+	}
+	pages := make(map[string]*QueryPages)
+	round := func() string {
+		var list []string
+		for _, task := range nextPageTasks(queries, pages) {
+			list = append(list, fmt.Sprintf("%s:%d", task.query, task.page))
+		}
+		return strings.Join(list, " ")
+	}
+
+	// first pages of the enabled queries, the duplicate row once
+	if got := round(); got != "ariel:1 elmex:1" {
+		t.Fatalf("round 1 = %q", got)
+	}
+	// discovered pages capped by PAGES
+	pages["ariel"].Found = 7
+	pages["elmex"].Found = 2
+	if got := round(); got != "ariel:2 ariel:3 elmex:2" {
+		t.Fatalf("round 2 = %q", got)
+	}
+	if got := round(); got != "" {
+		t.Fatalf("round 3 = %q, want nothing", got)
+	}
+}
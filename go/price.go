@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RegExps
+var (
+	// částka + měna
+	rePrice = regexp.MustCompile(`(-?\d+(?:[.,]\d+)?)\s*(Kč|CZK|€|EUR)?`)
+	// sleva v procentech
+	reDiscount = regexp.MustCompile(`(\d+)\s*%`)
+)
+
+// allowed difference between the shown and the computed discount, percentage points
+const ORIGINAL_PRICE_TOLERANCE = 1
+
+// currencies
+var currencyCodes = map[string]string{
+	"":    "CZK",
+	"Kč":  "CZK",
+	"CZK": "CZK",
+	"€":   "EUR",
+	"EUR": "EUR",
+}
+
+// compactPrice - helper function to remove all kinds of spaces from price strings
+func compactPrice(s string) string {
+	s = strings.ReplaceAll(s, "\u00a0", "")
+	s = strings.ReplaceAll(s, " ", "")
+	return s
+}
+
+// parsePrice - parse price string like "1 299.00 Kč" into haléře and currency code
+func parsePrice(s string) (int, string, bool) {
+	s = compactPrice(s)
+	match := rePrice.FindStringSubmatch(s)
+	if len(match) < 3 {
+		return 0, "", false
+	}
+	value, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, "", false
+	}
+	currency, ok := currencyCodes[match[2]]
+	if !ok {
+		return 0, "", false
+	}
+	return int(math.Round(value * 100)), currency, true
+}
+
+// parsePricePerUnit - parse unit price like "61.36 Kč / 1 l" into haléře and unit ("1 l")
+func parsePricePerUnit(s string) (int, string, bool) {
+	before, after, found := strings.Cut(s, "/")
+	if !found {
+		return 0, "", false
+	}
+	amount, _, ok := parsePrice(before)
+	if !ok {
+		return 0, "", false
+	}
+	unit := strings.ReplaceAll(after, "\u00a0", " ")
+	unit = sanitizeString(unit)
+	if unit == "" {
+		return 0, "", false
+	}
+	return amount, unit, true
+}
+
+// parseDiscount - parse discount like "-20 %" into percentage (20)
+func parseDiscount(s string) int {
+	match := reDiscount.FindStringSubmatch(compactPrice(s))
+	if len(match) < 2 {
+		return 0
+	}
+	pct, _ := strconv.Atoi(match[1])
+	return pct
+}
+
+// parsePrices - fill typed price fields from display strings
+func parsePrices(g *Goods) {
+	if amount, currency, ok := parsePrice(g.Price); ok {
+		g.PriceHal = amount
+		g.Currency = currency
+	}
+	if amount, unit, ok := parsePricePerUnit(g.PricePerUnit); ok {
+		g.PricePerUnitHal = amount
+		g.PricePerUnitUnit = unit
+	}
+	g.DiscountPct = parseDiscount(g.Discount)
+	parseOriginalPrice(g)
+}
+
+// parseOriginalPrice - regular price before the discount, scraped from the listing or derived from the percentage
+func parseOriginalPrice(g *Goods) {
+	g.OriginalPriceHal, g.OriginalDerived, g.SuspiciousPrice = 0, false, false
+	if g.PriceHal == 0 {
+		return
+	}
+
+	if amount, _, ok := parsePrice(g.OriginalPrice); ok && amount > 0 {
+		g.OriginalPriceHal = amount
+		g.SuspiciousPrice = !discountMatches(g.PriceHal, amount, g.DiscountPct)
+		return
+	}
+
+	switch {
+	case g.DiscountPct >= 100:
+		g.SuspiciousPrice = true
+	case g.DiscountPct > 0:
+		g.OriginalPriceHal = int(math.Round(float64(g.PriceHal) * 100 / float64(100-g.DiscountPct)))
+		g.OriginalDerived = true
+	}
+}
+
+// discountMatches - helper function to check the price against the original price and the shown percentage,
+// the percentage may be rounded either way
+func discountMatches(price, original, pct int) bool {
+	if original <= price {
+		return false
+	}
+	if pct == 0 {
+		return true
+	}
+	exact := float64(original-price) * 100 / float64(original)
+	return math.Abs(exact-float64(pct)) < ORIGINAL_PRICE_TOLERANCE
+}
+
+// reportPrices - log offers where the price, original price and discount disagree
+func reportPrices(goods []Goods) {
+	scraped, derived, suspicious := 0, 0, 0
+	for _, g := range goods {
+		switch {
+		case g.OriginalDerived:
+			derived++
+		case g.OriginalPriceHal > 0:
+			scraped++
+		}
+		if g.SuspiciousPrice {
+			suspicious++
+			log.Printf("🤨 suspicious price %s%s%s (%s): %s, original %s, discount %s", ColorBold, g.Name, ColorReset, g.Market, g.Price, g.OriginalPrice, g.Discount)
+		}
+	}
+	log.Printf("🏷️ original prices: %d scraped, %d derived, %s%d%s suspicious", scraped, derived, ColorRed, suspicious, ColorReset)
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		in       string
+		amount   int
+		currency string
+	}{
+		{"29.90 Kč", 2990, "CZK"},
+		{"1 299,50 Kč", 129950, "CZK"},
+		{"4.99 €", 499, "EUR"},
+		{"89", 8900, "CZK"},
+	}
+	for _, tt := range tests {
+		amount, currency, ok := parsePrice(tt.in)
+		if !ok || amount != tt.amount || currency != tt.currency {
+			t.Errorf("parsePrice(%q) = %d %s %v, want %d %s", tt.in, amount, currency, ok, tt.amount, tt.currency)
+		}
+	}
+	if _, _, ok := parsePrice("zdarma"); ok {
+		t.Error(`parsePrice("zdarma") parsed a price`)
+	}
+}
+
+func TestParseDiscount(t *testing.T) {
+	for in, want := range map[string]int{
+		"-20 %":      20,
+		"– 35 %":     35,
+		"1+1 zdarma": 0,
+	} {
+		if got := parseDiscount(in); got != want {
+			t.Errorf("parseDiscount(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestParseOriginalPrice(t *testing.T) {
+	tests := []struct {
+		price, original, discount string
+		originalHal               int
+		derived, suspicious       bool
+	}{
+		{"79.90 Kč", "99.90 Kč", "-20 %", 9990, false, false},
+		{"39.90 Kč", "49.90 Kč", "-21 %", 4990, false, false}, // 20.04 % rounded up
+		{"79.90 Kč", "99.90 Kč", "-35 %", 9990, false, true},  // percentage disagrees
+		{"99.90 Kč", "89.90 Kč", "-10 %", 8990, false, true},  // original below the price
+		{"79.90 Kč", "", "-20 %", 9988, true, false},          // derived fallback
+		{"79.90 Kč", "", "-100 %", 0, false, true},
+	}
+	for _, tt := range tests {
+		g := Goods{Price: tt.price, OriginalPrice: tt.original, Discount: tt.discount}
+		parsePrices(&g)
+		if g.OriginalPriceHal != tt.originalHal || g.OriginalDerived != tt.derived || g.SuspiciousPrice != tt.suspicious {
+			t.Errorf("%s, original %q, %s = %d derived %v suspicious %v, want %d %v %v", tt.price, tt.original, tt.discount,
+				g.OriginalPriceHal, g.OriginalDerived, g.SuspiciousPrice, tt.originalHal, tt.derived, tt.suspicious)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	PROMO_FREE = "free" // 1+1, 2+1 zdarma
+	PROMO_HALF = "half" // druhý za polovic
+	PROMO_MIN  = "min"  // akční cena od 3 ks
+)
+
+// RegExps
+var (
+	// 1+1, 2+1 zdarma, 4+2
+	rePromoFree = regexp.MustCompile(`(?:^|[\s(])([1-5])\+([1-5])(?:\s+zdarma)?(?:$|[\s,)])`)
+	// druhý za polovic, druhý kus za polovinu
+	rePromoHalf = regexp.MustCompile(`druh(?:ý|á|é)(?:\s+(?:kus|ks|balení))?\s+za\s+polovi`)
+	// akční cena pouze při zakoupení 2 ks, cena platí při koupi 3 ks, od 3 ks, 2 a více balení
+	rePromoMin = regexp.MustCompile(`(?:(?:akční )?cena (?:platí )?(?:pouze )?při (?:zakoupení|koupi|nákupu)|\bod)\s+(\d+)(?:\s*[‑-]\s*\d+)?\s*(?:a více\s*)?(?:ks|kus|balení)`)
+	// max. 7 ks/osoba/den
+	rePurchaseLimit = regexp.MustCompile(`\bmax\.\s*(\d+)\s*(?:ks|kus|balení)`)
+)
+
+// parsePurchaseLimit - maximum pieces per purchase like "max. 7 ks/osoba/den", 0 if unlimited
+func parsePurchaseLimit(s string) int {
+	match := rePurchaseLimit.FindStringSubmatch(s)
+	if len(match) < 2 {
+		return 0
+	}
+	limit, _ := strconv.Atoi(match[1])
+	return limit
+}
+
+// parsePromotion - parse the multi-buy promotion and the effective price of one piece
+func parsePromotion(g *Goods) {
+	g.PromoType, g.PromoMinQty = "", 0
+	g.EffectivePriceHal = g.PriceHal
+
+	// typoFix glues numbers and units with non-breaking spaces
+	nbsp := strings.NewReplacer("\u00a0", " ")
+	text := strings.ToLower(nbsp.Replace(g.Note + ", " + g.Discount))
+
+	// the limit is often a part of the club condition
+	g.PurchaseLimit = parsePurchaseLimit(text + ", " + strings.ToLower(nbsp.Replace(g.Club)))
+
+	// the price is for one piece, the free pieces lower the price of each one
+	if match := rePromoFree.FindStringSubmatch(text); len(match) == 3 {
+		buy, _ := strconv.Atoi(match[1])
+		free, _ := strconv.Atoi(match[2])
+		g.PromoType = PROMO_FREE
+		g.PromoMinQty = buy + free
+		g.EffectivePriceHal = (g.PriceHal*buy + g.PromoMinQty/2) / g.PromoMinQty
+		return
+	}
+	if rePromoHalf.MatchString(text) {
+		g.PromoType = PROMO_HALF
+		g.PromoMinQty = 2
+		g.EffectivePriceHal = (g.PriceHal*3 + 2) / 4
+		return
+	}
+	if match := rePromoMin.FindStringSubmatch(text); len(match) == 2 {
+		minQty, _ := strconv.Atoi(match[1])
+		if minQty > 1 {
+			g.PromoType = PROMO_MIN
+			g.PromoMinQty = minQty
+		}
+	}
+}
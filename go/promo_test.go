@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParsePromotion(t *testing.T) {
+	tests := []struct {
+		note      string
+		club      string
+		promoType string
+		minQty    int
+		effective int
+		limit     int
+	}{
+		// real notes, typoFix puts a non-breaking space before the unit
+		{"akční cena pouze při zakoupení 2\u00a0ks", "", PROMO_MIN, 2, 10000, 0},
+		{"různé druhy, akční cena při zakoupení 3\u00a0ks", "", PROMO_MIN, 3, 10000, 0},
+		{"akční cena při zakoupení 2 balení", "", PROMO_MIN, 2, 10000, 0},
+		{"Cena platí při koupi 3\u00a0ks, různé odstíny", "", PROMO_MIN, 3, 10000, 0},
+		{"max. 6\u00a0ks/osoba/den", "", "", 0, 10000, 6},
+		{"", "pro členy klubu, max. 2\u00a0ks", "", 0, 10000, 2},
+		// gifts are not a minimum quantity
+		{"při koupi 2ks získáte tonikum 200\u00a0ml jako dárek", "", "", 0, 10000, 0},
+		{"1+1 zdarma", "", PROMO_FREE, 2, 5000, 0},
+		{"druhý kus za polovinu", "", PROMO_HALF, 2, 7500, 0},
+	}
+	for _, tt := range tests {
+		g := Goods{Note: tt.note, Club: tt.club, PriceHal: 10000}
+		parsePromotion(&g)
+		if g.PromoType != tt.promoType || g.PromoMinQty != tt.minQty || g.EffectivePriceHal != tt.effective || g.PurchaseLimit != tt.limit {
+			t.Errorf("parsePromotion(%q, %q) = %q min %d effective %d limit %d, want %q min %d effective %d limit %d", tt.note, tt.club,
+				g.PromoType, g.PromoMinQty, g.EffectivePriceHal, g.PurchaseLimit, tt.promoType, tt.minQty, tt.effective, tt.limit)
+		}
+	}
+}
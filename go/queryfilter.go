@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// optional per-query filter from scrape.csv
+//
+//	CATEGORY,QUERY,PAGES,INCLUDE,EXCLUDE,MINPRICE,MARKETS
+//	KOSMETIKA,balzám,2,,nádobí|myčk
+//
+// multiple terms or markets are separated by "|"
+type QueryFilter struct {
+	Include     []string // name or note must contain one of these terms
+	Exclude     []string // name or note must not contain any of these terms
+	MinPriceHal int      // minimal price in haléře
+	Markets     []string // allowed markets
+}
+
+// splitTerms - helper function to split "|" separated column into normalized terms
+func splitTerms(s string, normalize bool) []string {
+	var terms []string
+	for t := range strings.SplitSeq(s, "|") {
+		t = strings.TrimSpace(t)
+		if normalize {
+			t = normalizeCzechString(t)
+		}
+		if t != "" {
+			terms = append(terms, t)
+		}
+	}
+	return terms
+}
+
+// parseQueryFilter - read optional filter columns of scrape.csv record
+func parseQueryFilter(record []string) (QueryFilter, error) {
+	column := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var filter QueryFilter
+	filter.Include = splitTerms(column(3), true)
+	filter.Exclude = splitTerms(column(4), true)
+	filter.Markets = splitTerms(column(6), false)
+	if min := column(5); min != "" {
+		price, _, ok := parsePrice(min)
+		if !ok {
+			return filter, fmt.Errorf("bad MINPRICE %q", min)
+		}
+		filter.MinPriceHal = price
+	}
+	return filter, nil
+}
+
+// isEmpty - filter without any conditions
+func (f QueryFilter) isEmpty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0 && f.MinPriceHal == 0 && len(f.Markets) == 0
+}
+
+// accepts - check the goods against the filter
+func (f QueryFilter) accepts(g Goods) bool {
+	text := normalizeCzechString(g.Name + " " + g.Note)
+	if len(f.Include) > 0 {
+		found := false
+		for _, t := range f.Include {
+			if strings.Contains(text, t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, t := range f.Exclude {
+		if strings.Contains(text, t) {
+			return false
+		}
+	}
+	if f.MinPriceHal > 0 && g.PriceHal < f.MinPriceHal {
+		return false
+	}
+	if len(f.Markets) > 0 && !containsFold(f.Markets, g.Market) {
+		return false
+	}
+	return true
+}
+
+// filterGoods - keep only goods accepted by the query filter
+func filterGoods(goods []Goods, filter QueryFilter) []Goods {
+	if filter.isEmpty() {
+		return goods
+	}
+	var filtered []Goods
+	for _, g := range goods {
+		if filter.accepts(g) {
+			filtered = append(filtered, g)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// exported data file structure (only the parts needed to read it back)
+type exportedData struct {
+	Goods     []map[string]any  `json:"goods"`
+	IdHashmap map[string]string `json:"idhashmap"` // id → product hash, missing in old stems
+}
+
+// readExportedData - read koopi.json or a stems file
+func readExportedData(filename string) (exportedData, error) {
+	var data exportedData
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return data, err
+	}
+	err = json.Unmarshal(content, &data)
+	return data, err
+}
+
+// jsonString - helper function to read string value from decoded JSON
+func jsonString(item map[string]any, key string) string {
+	if s, ok := item[key].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// jsonInt - helper function to read integer value from decoded JSON
+func jsonInt(item map[string]any, key string) int {
+	if f, ok := item[key].(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+// goodsFromJsonItem - convert exported JSON item back to Goods
+func goodsFromJsonItem(item map[string]any) Goods {
+	var g Goods
+	g.Category = jsonString(item, "cat")
+	g.SubCat = jsonString(item, "subcat")
+	g.CatSource = jsonString(item, "catsrc")
+	g.Query = jsonString(item, "query")
+	g.Name = jsonString(item, "name")
+	g.Price = jsonString(item, "price")
+	g.PricePerUnit = jsonString(item, "ppunit")
+	g.Discount = jsonString(item, "discount")
+	g.OriginalPrice = jsonString(item, "original")
+	g.Note = jsonString(item, "note")
+	g.Club = stripClubIcons(jsonString(item, "club"))
+	g.Volume = jsonString(item, "volume")
+	g.Market = jsonString(item, "market")
+	g.Validity = jsonString(item, "validity")
+	g.ScrapedAt = jsonString(item, "scrapedat")
+	g.Description = jsonString(item, "description")
+	g.LargeImageUrl = jsonString(item, "image_large")
+	g.Pinned, _ = item["pinned"].(bool)
+
+	if u := jsonString(item, "url"); u != "" {
+		g.Url = KOOPI_HOME_URL + u
+	}
+	if img := jsonString(item, "image"); img != "" && img != "default.webp" {
+		g.ImageUrl = KOOPI_THUMBS_URL + img
+	}
+
+	// typed values
+	parsePrices(&g)
+	if g.PricePerUnitHal == 0 {
+		// unit price text is blanked when it equals price/volume
+		g.PricePerUnitHal = jsonInt(item, "ppunit_hal")
+		g.PricePerUnitUnit = jsonString(item, "ppunit_unit")
+	}
+	parseVolumes(&g)
+	parseValidityFields(&g)
+	parseDoses(&g)
+	parsePromotion(&g)
+	parseClub(&g)
+
+	return g
+}
+
+// loadGoodsFromJson - load goods from koopi.json or a stems file
+func loadGoodsFromJson(filename string) ([]Goods, error) {
+	data, err := readExportedData(filename)
+	if err != nil {
+		return nil, err
+	}
+	return data.goods(), nil
+}
+
+// goods - decoded goods with the product hashes stored in the idhashmap
+func (data exportedData) goods() []Goods {
+	goods := make([]Goods, 0, len(data.Goods))
+	for _, item := range data.Goods {
+		g := goodsFromJsonItem(item)
+		if g.Name == "" {
+			continue
+		}
+		g.Hash = data.IdHashmap[strconv.Itoa(jsonInt(item, "id"))]
+		goods = append(goods, g)
+	}
+	return goods
+}
+
+// sortedMarkets - list of unique markets sorted the Czech way
+func sortedMarkets(goods []Goods) []string {
+	seen := make(map[string]bool)
+	var markets []string
+	for _, g := range goods {
+		if g.Market != "" && !seen[g.Market] {
+			seen[g.Market] = true
+			markets = append(markets, g.Market)
+		}
+	}
+	c := collate.New(language.Czech, collate.IgnoreCase)
+	sort.Slice(markets, func(i, j int) bool {
+		return c.CompareString(markets[i], markets[j]) < 0
+	})
+	return markets
+}
+
+// rehydrate - recompute time dependent fields of the exported data without scraping
+func rehydrate(args []string) {
+	input := config.Paths.OutputJson
+	if len(args) > 0 {
+		input = args[0]
+	}
+
+	goods, err := loadGoodsFromJson(input)
+	if err != nil {
+		log.Fatalf("[%s] 💥 error loading: %v", input, err)
+	}
+
+	// drop expired offers
+	now := time.Now()
+	var activeGoods []Goods
+	for _, g := range goods {
+		if _, _, expired := validityState(g, now); expired {
+			continue
+		}
+		activeGoods = append(activeGoods, g)
+	}
+
+	applyPriceHistory(activeGoods)
+
+	var mutex sync.Mutex
+	appendToJson(activeGoods, config.Paths.OutputJson, sortedMarkets(activeGoods), &mutex)
+	exportHistory(activeGoods, config.Paths.OutputHistory)
+
+	fmt.Printf("\n💧 Rehydrated %s: %d items, %d expired, saved to %s.\n\n", input, len(activeGoods), len(goods)-len(activeGoods), config.Paths.OutputJson)
+}
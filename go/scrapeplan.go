@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// scrape.csv query row
+type ScrapeQuery struct {
+	Line     int
+	Category string
+	Query    string
+	Pages    int
+	Filter   QueryFilter
+}
+
+// readScrapeCsv - read scrape.csv queries, malformed rows are skipped and reported
+//
+// lines starting with "#" are comments, the first row may be the CATEGORY,QUERY,PAGES header
+func readScrapeCsv(filename string) ([]ScrapeQuery, []string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ','
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	var queries []ScrapeQuery
+	var problems []string
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "CATEGORY") {
+			continue
+		}
+		problem := func(format string, args ...any) {
+			problems = append(problems, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+		}
+
+		if len(record) < 3 || len(record) > 7 {
+			problem("expected 3 to 7 columns, got %d: %q", len(record), strings.Join(record, ","))
+			continue
+		}
+		q := ScrapeQuery{
+			Line:     line,
+			Category: taxonomy.resolve(strings.TrimSpace(record[0])),
+			Query:    strings.TrimSpace(record[1]),
+		}
+		if q.Category == "" || q.Query == "" {
+			problem("empty category or query")
+			continue
+		}
+		q.Pages, err = strconv.Atoi(strings.TrimSpace(record[2]))
+		if err != nil || q.Pages < 0 {
+			problem("%s: bad PAGES %q", q.Query, record[2])
+			continue
+		}
+		if q.Filter, err = parseQueryFilter(record); err != nil {
+			problem("%s: %v", q.Query, err)
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return queries, problems, nil
+}
+
+// task - one result page of the query
+func (q ScrapeQuery) task(pageNum int) ScrapeTask {
+	escapedQuery := url.QueryEscape(q.Query)
+	urlStr := KOOPI_SEARCH_URL + escapedQuery
+	if pageNum > 1 {
+		urlStr = fmt.Sprintf("%s%s%s%d", KOOPI_SEARCH_URL, escapedQuery, KOOPI_SUBPAGE, pageNum)
+	}
+	cacheKey := fmt.Sprintf("%s-%d.html", strings.ReplaceAll(q.Query, " ", "-"), pageNum)
+	return ScrapeTask{urlStr, cacheKey, q.Category, q.Query, pageNum, q.Filter}
+}
+
+// planPages - first pages scraped for sure and the upper bound of all pages, duplicate rows are scraped once
+func planPages(queries []ScrapeQuery) ([]ScrapeTask, int) {
+	first := nextPageTasks(queries, make(map[string]*QueryPages))
+	upper := 0
+	seen := make(map[string]bool)
+	for _, q := range queries {
+		if !seen[q.Query] {
+			seen[q.Query] = true
+			upper += q.Pages
+		}
+	}
+	return first, upper
+}
+
+// lintScrapeQueries - check the queries, returns errors and warnings
+func lintScrapeQueries(queries []ScrapeQuery) ([]string, []string) {
+	var errors, warnings []string
+
+	var unique []ScrapeQuery
+	seenQueries := make(map[string]ScrapeQuery)
+	seenCache := make(map[string]ScrapeQuery)
+	for _, q := range queries {
+		if !taxonomy.known(q.Category) {
+			errors = append(errors, fmt.Sprintf("line %d: %s: unknown category %s", q.Line, q.Query, q.Category))
+		}
+		if q.Pages == 0 {
+			warnings = append(warnings, fmt.Sprintf("line %d: %s: 0 pages, query is disabled", q.Line, q.Query))
+		}
+		key := strings.ToLower(q.Query)
+		if prev, ok := seenQueries[key]; ok {
+			errors = append(errors, fmt.Sprintf("line %d: %s: duplicates line %d", q.Line, q.Query, prev.Line))
+			continue
+		}
+		seenQueries[key] = q
+		unique = append(unique, q)
+
+		// different queries sharing the same cache files
+		cacheKey := strings.ReplaceAll(key, " ", "-")
+		if prev, ok := seenCache[cacheKey]; ok {
+			errors = append(errors, fmt.Sprintf("line %d: %s: same cache files as %q on line %d", q.Line, q.Query, prev.Query, prev.Line))
+		} else {
+			seenCache[cacheKey] = q
+		}
+	}
+
+	// the query itself has to get its own deterministic category
+	matcher := newCategoryMatcher(categoryRules(unique, taxonomy))
+	for _, q := range unique {
+		if q.Pages == 0 {
+			continue
+		}
+		best, found := matcher.match(q.Query)
+		if best != nil && best.Category != q.Category {
+			errors = append(errors, fmt.Sprintf("line %d: %s (%s) is shadowed by %s", q.Line, q.Query, q.Category, best))
+		}
+		for _, i := range found {
+			r := matcher.rules[i]
+			if r.Source == "query" && r.Pattern != strings.ToLower(q.Query) {
+				warnings = append(warnings, fmt.Sprintf("line %d: %s contains query %q (%s)", q.Line, q.Query, r.Pattern, r.Category))
+			}
+		}
+	}
+
+	// the first pages are always scraped, the others only when the pagination links them
+	first, upper := planPages(queries)
+	if len(first) > MAX_SCRAPED_GOODS {
+		errors = append(errors, fmt.Sprintf("%d first pages exceed MAX_SCRAPED_GOODS %d, %d random queries would be skipped", len(first), MAX_SCRAPED_GOODS, len(first)-MAX_SCRAPED_GOODS))
+	} else if upper > MAX_SCRAPED_GOODS {
+		warnings = append(warnings, fmt.Sprintf("up to %d pages exceed MAX_SCRAPED_GOODS %d in the worst case, the last discovered pages would be skipped", upper, MAX_SCRAPED_GOODS))
+	}
+	return errors, warnings
+}
+
+// lintCommand - check scrape.csv and print the crawl plan without fetching anything
+func lintCommand(args []string) bool {
+	filename := config.Paths.InputCsv
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	queries, problems, err := readScrapeCsv(filename)
+	if err != nil {
+		fmt.Printf("💥 [%s] %v\n", filename, err)
+		return false
+	}
+	errors, warnings := lintScrapeQueries(queries)
+	errors = append(problems, errors...)
+
+	// crawl plan, PAGES is the upper bound of the pages discovered at runtime
+	maxPages := make(map[string]int)
+	for _, q := range queries {
+		if _, ok := maxPages[q.Query]; !ok {
+			maxPages[q.Query] = q.Pages
+		}
+	}
+	tasks, upper := planPages(queries)
+	cached := 0
+	for _, t := range tasks {
+		source := "🌐"
+		if _, err := os.Stat(filepath.Join(config.Paths.HtmlCache, t.cacheKey)); err == nil {
+			source = "💾"
+			cached++
+		}
+		filter := ""
+		if !t.filter.isEmpty() {
+			filter = fmt.Sprintf(" %s%+v%s", ColorBlue, t.filter, ColorReset)
+		}
+		more := ""
+		if maxPages[t.query] > 1 {
+			more = fmt.Sprintf(" %s… up to page %d%s", ColorDim, maxPages[t.query], ColorReset)
+		}
+		fmt.Printf("%s %-10s %s%s%s %s%s%s%s%s\n", source, t.category, ColorBold, t.query, ColorReset, ColorCyan, t.url, ColorReset, more, filter)
+	}
+	fmt.Println()
+
+	for _, w := range warnings {
+		fmt.Printf("⚠️ %s\n", w)
+	}
+	for _, e := range errors {
+		fmt.Printf("❌ %s\n", e)
+	}
+	fmt.Printf("\n📋 [%s] %d queries, %d first pages (%d cached, %d to fetch), up to %d pages (limit %d), %d errors, %d warnings.\n\n", filename,
+		len(queries), len(tasks), cached, len(tasks)-cached, upper, MAX_SCRAPED_GOODS, len(errors), len(warnings))
+	return len(errors) == 0
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DATE_ISO     = "2006-01-02"
+	DATE_SCRAPED = "20060102"
+
+	VALIDITY_FUTURE_HOURS = 120
+)
+
+// RegExps
+var (
+	// den. měsíc. [rok]
+	reValidityDate = regexp.MustCompile(`(\d{1,2})\.\s*(\d{1,2})\.(?:\s*(\d{4}))?`)
+)
+
+// dayStart - helper function to truncate time to local midnight
+func dayStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// nearestDate - helper function to pick the year closest to the reference date (year rollover)
+func nearestDate(d, m int, ref time.Time) time.Time {
+	best := time.Time{}
+	for _, year := range []int{ref.Year() - 1, ref.Year(), ref.Year() + 1} {
+		t := time.Date(year, time.Month(m), d, 0, 0, 0, 0, time.Local)
+		if best.IsZero() || absDuration(t.Sub(ref)) < absDuration(best.Sub(ref)) {
+			best = t
+		}
+	}
+	return best
+}
+
+// absDuration - helper function for absolute duration
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// parseValidityDates - helper function to find all dates in Czech validity text
+func parseValidityDates(s string, ref time.Time) []time.Time {
+	var dates []time.Time
+	for _, match := range reValidityDate.FindAllStringSubmatch(s, -1) {
+		d, _ := strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2])
+		if d < 1 || d > 31 || m < 1 || m > 12 {
+			continue
+		}
+		if match[3] != "" {
+			y, _ := strconv.Atoi(match[3])
+			dates = append(dates, time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.Local))
+			continue
+		}
+		dates = append(dates, nearestDate(d, m, ref))
+	}
+	return dates
+}
+
+// parseValidity - parse Czech validity text into start and end date relative to the scrape date
+//
+//	"platí do středy 19. 8."  -> scraped .. 19. 8.
+//	"st 13. 5. – út 19. 5."   -> 13. 5. .. 19. 5.
+//	"platí od čtvrtka 23. 7." -> 23. 7. .. unknown
+//	"zítra končí"             -> scraped .. scraped+1
+//	"aktuální"                -> scraped .. unknown
+func parseValidity(validity string, scraped time.Time) (time.Time, time.Time) {
+	scraped = dayStart(scraped)
+	lower := strings.ToLower(validity)
+
+	switch {
+	case strings.Contains(lower, "dnes končí"):
+		return scraped, scraped
+	case strings.Contains(lower, "zítra končí"):
+		return scraped, scraped.AddDate(0, 0, 1)
+	case strings.Contains(lower, "od zítra"):
+		return scraped.AddDate(0, 0, 1), time.Time{}
+	}
+
+	dates := parseValidityDates(validity, scraped)
+	switch {
+	case len(dates) >= 2:
+		from, to := dates[0], dates[1]
+		for to.Before(from) {
+			to = to.AddDate(1, 0, 0)
+		}
+		return from, to
+	case len(dates) == 1 && strings.Contains(" "+lower+" ", " od "):
+		return dates[0], time.Time{}
+	case len(dates) == 1:
+		return scraped, dates[0]
+	}
+
+	return scraped, time.Time{}
+}
+
+// parseValidityFields - fill ISO validity dates and upcoming flag from Validity and ScrapedAt
+func parseValidityFields(g *Goods) {
+	scraped, err := time.ParseInLocation(DATE_SCRAPED, g.ScrapedAt, time.Local)
+	if err != nil {
+		g.ValidFrom, g.ValidTo, g.Upcoming = "", "", false
+		return
+	}
+	from, to := parseValidity(g.Validity, scraped)
+	g.ValidFrom, g.ValidTo = formatIsoDate(from), formatIsoDate(to)
+	g.Upcoming = from.After(dayStart(scraped))
+}
+
+// formatIsoDate - helper function to format date, zero time is an empty string
+func formatIsoDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DATE_ISO)
+}
+
+// parseIsoDate - helper function to parse date, empty string is a zero time
+func parseIsoDate(s string) time.Time {
+	t, err := time.ParseInLocation(DATE_ISO, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// validityState - compute display text, color and expiration of the offer at the given time
+func validityState(g Goods, now time.Time) (string, string, bool) {
+	today := dayStart(now)
+	tomorrow := today.AddDate(0, 0, 1)
+	from, to := parseIsoDate(g.ValidFrom), parseIsoDate(g.ValidTo)
+
+	// expired
+	if !to.IsZero() && to.Before(today) {
+		return g.Validity, "", true
+	}
+
+	// relative texts are rewritten to the current day
+	validity := g.Validity
+	if strings.Contains(validity, "dnes končí") || strings.Contains(validity, "zítra končí") {
+		if to.Equal(today) {
+			validity = "dnes končí"
+		} else if to.Equal(tomorrow) {
+			validity = "zítra končí"
+		}
+	}
+
+	// color matching
+	valcol := "green"
+	switch {
+	case to.Equal(today):
+		valcol = "red"
+	case to.Equal(tomorrow):
+		valcol = "orange"
+	case from.After(today) && from.Sub(now).Hours() > VALIDITY_FUTURE_HOURS:
+		valcol = "blue"
+	case !to.IsZero() && to.Sub(now).Hours() > VALIDITY_FUTURE_HOURS:
+		valcol = "blue"
+	}
+
+	return validity, valcol, false
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseValidity(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	scraped := time.Date(2025, time.August, 17, 13, 45, 0, 0, time.Local)
+	tests := map[string]struct {
+		validity string
+		scraped  time.Time
+		from, to time.Time
+	}{
+		"end only":      {"platí do středy 19. 8.", scraped, day(2025, 8, 17), day(2025, 8, 19)},
+		"range":         {"st 13. 8. – út 19. 8.", scraped, day(2025, 8, 13), day(2025, 8, 19)},
+		"start only":    {"platí od čtvrtka 21. 8.", scraped, day(2025, 8, 21), time.Time{}},
+		"relative":      {"zítra končí", scraped, day(2025, 8, 17), day(2025, 8, 18)},
+		"year rollover": {"po 29. 12. – ne 4. 1.", day(2025, 12, 29), day(2025, 12, 29), day(2026, 1, 4)},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			from, to := parseValidity(tt.validity, tt.scraped)
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("parseValidity(%q) = %s .. %s, want %s .. %s", tt.validity,
+					from.Format(DATE_ISO), to.Format(DATE_ISO), tt.from.Format(DATE_ISO), tt.to.Format(DATE_ISO))
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// [počet x] množství[-množství] jednotka
+const volumePattern = `(?:(\d+)\s*x\s*)?(\d+(?:[.,]\d+)?)(?:\s*[-‑–]\s*(\d+(?:[.,]\d+)?))?\s*(kg|mg|g|ml|cl|dl|l|ks|dávek|dávky|dávka)`
+
+// RegExps
+var (
+	// objem kdekoliv v textu
+	reVolume = regexp.MustCompile(`(?i)` + volumePattern + `(?:[^\p{L}]|$)`)
+	// objem jako samostatná část poznámky ("objem: 5-10 l")
+	reVolumeNote = regexp.MustCompile(`(?i)^(?:[\p{L} ]+:\s*)?` + volumePattern + `$`)
+)
+
+// volume units converted to base units (g/ml/ks/dávka)
+var volumeUnits = map[string]struct {
+	base   string
+	factor float64
+}{
+	"mg":    {"g", 0.001},
+	"g":     {"g", 1},
+	"kg":    {"g", 1000},
+	"ml":    {"ml", 1},
+	"cl":    {"ml", 10},
+	"dl":    {"ml", 100},
+	"l":     {"ml", 1000},
+	"ks":    {"ks", 1},
+	"dávek": {"dávka", 1},
+	"dávky": {"dávka", 1},
+	"dávka": {"dávka", 1},
+}
+
+// parsed volume
+type Volume struct {
+	Qty      float64 // total quantity in base unit (lower bound for ranges)
+	QtyMax   float64 // total quantity in base unit (upper bound for ranges)
+	Unit     string  // g, ml, ks or dávka
+	Packs    int     // number of pieces in a multipack
+	PieceQty float64 // quantity of one piece in base unit
+}
+
+// parseCzechFloat - helper function to parse numbers with decimal comma
+func parseCzechFloat(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// roundQty - helper function to get rid of float noise
+func roundQty(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+// parseVolume - parse volume like "1.628 l", "3x 100 ml" or "1,5‑1,7 kg"
+func parseVolume(s string) (Volume, bool) {
+	return matchVolume(reVolume, s)
+}
+
+// parseNoteVolume - parse volume from a standalone part of the note ("různé druhy, 250-400 ml")
+func parseNoteVolume(note string) (Volume, bool) {
+	for part := range strings.SplitSeq(note, ", ") {
+		if vol, ok := matchVolume(reVolumeNote, strings.TrimSpace(part)); ok {
+			return vol, true
+		}
+	}
+	return Volume{}, false
+}
+
+// matchVolume - helper function to convert volume regexp match to base units
+func matchVolume(re *regexp.Regexp, s string) (Volume, bool) {
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	match := re.FindStringSubmatch(s)
+	if len(match) < 5 {
+		return Volume{}, false
+	}
+	unit, ok := volumeUnits[strings.ToLower(match[4])]
+	if !ok {
+		return Volume{}, false
+	}
+
+	packs := 1
+	if match[1] != "" {
+		packs, _ = strconv.Atoi(match[1])
+		if packs < 1 {
+			packs = 1
+		}
+	}
+	piece, err := parseCzechFloat(match[2])
+	if err != nil || piece <= 0 {
+		return Volume{}, false
+	}
+	pieceMax := piece
+	if match[3] != "" {
+		if f, err := parseCzechFloat(match[3]); err == nil && f > piece {
+			pieceMax = f
+		}
+	}
+
+	return Volume{
+		Qty:      roundQty(float64(packs) * piece * unit.factor),
+		QtyMax:   roundQty(float64(packs) * pieceMax * unit.factor),
+		Unit:     unit.base,
+		Packs:    packs,
+		PieceQty: roundQty(piece * unit.factor),
+	}, true
+}
+
+// parseVolumes - fill typed volume fields from Volume (or Note as a fallback)
+func parseVolumes(g *Goods) {
+	vol, ok := parseVolume(g.Volume)
+	if !ok && g.Volume == "" {
+		vol, ok = parseNoteVolume(g.Note)
+	}
+	if !ok {
+		return
+	}
+	g.VolQty = vol.Qty
+	g.VolMax = vol.QtyMax
+	g.VolUnit = vol.Unit
+	g.VolPacks = vol.Packs
+	g.VolPiece = vol.PieceQty
+}
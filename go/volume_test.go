@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestParseVolume(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Volume
+	}{
+		// unit conversion
+		{"1.628 l", Volume{Qty: 1628, QtyMax: 1628, Unit: "ml", Packs: 1, PieceQty: 1628}},
+		// multipack, the quantity is the total
+		{"3x 100 ml", Volume{Qty: 300, QtyMax: 300, Unit: "ml", Packs: 3, PieceQty: 100}},
+		// range with the non-breaking hyphen and decimal comma
+		{"1,5‑1,7 kg", Volume{Qty: 1500, QtyMax: 1700, Unit: "g", Packs: 1, PieceQty: 1500}},
+	}
+	for _, tt := range tests {
+		got, ok := parseVolume(tt.in)
+		if !ok || got != tt.want {
+			t.Errorf("parseVolume(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	if vol, ok := parseVolume("5 lahví"); ok {
+		t.Errorf("parseVolume(%q) = %+v, want no volume", "5 lahví", vol)
+	}
+}
+
+func TestParseNoteVolume(t *testing.T) {
+	vol, ok := parseNoteVolume("různé druhy, 250‑400 ml")
+	if !ok || vol.Qty != 250 || vol.QtyMax != 400 || vol.Unit != "ml" {
+		t.Errorf("parseNoteVolume = %+v, %v, want 250-400 ml", vol, ok)
+	}
+	// volume inside a purchase condition is not the volume of the goods
+	if vol, ok := parseNoteVolume("akční cena při zakoupení 2 ks"); ok {
+		t.Errorf("parseNoteVolume = %+v, want no volume", vol)
+	}
+}